@@ -3,12 +3,19 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"nexablog/config"
 	"nexablog/db"
 	"nexablog/internal/app"
+	"nexablog/internal/logging"
+	"nexablog/internal/mailer"
+	"nexablog/internal/ratelimit"
+	"nexablog/internal/telemetry"
 )
 
 func main() {
@@ -17,6 +24,31 @@ func main() {
 		log.Fatal(err)
 	}
 
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := telemetry.SetupTracing(context.Background(), telemetry.TracingConfig{
+		Exporter:    cfg.Tracing.Exporter,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		Stdout:      os.Stdout,
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("could not flush traces", "error", err)
+		}
+	}()
+
 	database, err := db.New(db.Config{
 		Uri:       cfg.DB.Uri,
 		IdleConns: cfg.DB.IdleConns,
@@ -24,17 +56,69 @@ func main() {
 		IdleTime:  cfg.DB.IdleTime,
 	})
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(database, os.Args[2:]); err != nil {
+			fatal(err)
+		}
+
+		return
+	}
+
+	if cfg.AutoMigrate {
+		if err := autoMigrate(database); err != nil {
+			fatal(err)
+		}
+	}
+
+	m, err := mailer.New(mailer.Config{
+		Driver:       cfg.Mail.Driver,
+		From:         cfg.Mail.From,
+		Dir:          cfg.Mail.Dir,
+		SMTPHost:     cfg.Mail.SMTPHost,
+		SMTPPort:     cfg.Mail.SMTPPort,
+		SMTPUsername: cfg.Mail.SMTPUsername,
+		SMTPPassword: cfg.Mail.SMTPPassword,
+	})
+	if err != nil {
+		fatal(err)
 	}
 
-	appl := app.New(cfg, database)
+	limiter, err := ratelimit.New(cfg.RateLimit.Store)
+	if err != nil {
+		fatal(err)
+	}
 
-	signals := []os.Signal{os.Interrupt, os.Kill}
+	appl, err := app.New(cfg, database, m, limiter)
+	if err != nil {
+		fatal(err)
+	}
+
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), signals...)
 	defer cancel()
 
 	if err := appl.StartAndRun(ctx); err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 }
+
+func autoMigrate(database *db.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	m, err := database.Migrator()
+	if err != nil {
+		return err
+	}
+
+	return m.Up(ctx)
+}
+
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"nexablog/db"
+)
+
+var errMigrateUsage = errors.New("usage: nexablog migrate up|down|status|to N")
+
+func migrate(database *db.DB, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	m, err := database.Migrator()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		return m.Up(ctx)
+	case args[0] == "down" && len(args) == 1:
+		return m.Down(ctx)
+	case args[0] == "status" && len(args) == 1:
+		return printStatus(ctx, m)
+	case args[0] == "to" && len(args) == 2:
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return errMigrateUsage
+		}
+
+		return m.To(ctx, version)
+	default:
+		return errMigrateUsage
+	}
+}
+
+func printStatus(ctx context.Context, m *db.Migrator) error {
+	list, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+
+	for _, s := range list {
+		status, appliedAt := "pending", ""
+
+		if s.AppliedAt != nil {
+			status, appliedAt = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+
+		switch {
+		case s.Unknown:
+			status = "applied, not in this build"
+		case s.Modified:
+			status = "applied, modified since"
+		}
+
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+	}
+
+	return w.Flush()
+}
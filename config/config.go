@@ -4,21 +4,85 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 var ErrNoValue = errors.New("no value")
 
+var providerName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// RateLimitGroups are the route groups that can be given their own limit with
+// RATE_LIMIT_<GROUP>. Groups without one share the default limit.
+var RateLimitGroups = []string{"default", "users", "tokens", "oidc", "admin", "posts", "tags", "feeds"}
+
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+type OIDCProvider struct {
+	Name,
+	Issuer,
+	ClientID,
+	ClientSecret string
+	Scopes []string
+}
+
 type Config struct {
-	Port string
-	DB   struct {
+	Port              string
+	AdminHost         string
+	AdminPort         string
+	SiteURL           string
+	SiteTitle         string
+	SchedulerInterval int
+	ShutdownDelay     time.Duration
+	AutoMigrate       bool
+	Token             struct {
+		AccessTTL,
+		RefreshTTL time.Duration
+	}
+	Log struct {
+		Format,
+		Level string
+	}
+	Tracing struct {
+		Exporter    string
+		SampleRatio float64
+	}
+	RateLimit struct {
+		Store  string
+		Limits map[string]RateLimit
+	}
+	Lockout struct {
+		AccountBackoffAfter,
+		IPBackoffAfter,
+		LockAfter int
+		BackoffBase,
+		BackoffMax,
+		LockDuration,
+		Window time.Duration
+	}
+	DB struct {
 		Uri string
 		IdleConns,
 		OpenConns,
 		IdleTime int
 	}
+	OIDC []OIDCProvider
+	Mail struct {
+		Driver,
+		From,
+		Dir,
+		SMTPHost,
+		SMTPUsername,
+		SMTPPassword string
+		SMTPPort int
+	}
 }
 
 func New() (*Config, error) {
@@ -32,6 +96,40 @@ func New() (*Config, error) {
 		port = fmt.Sprint(8000)
 	}
 
+	adminHost := os.Getenv("ADMIN_HOST")
+	if adminHost == "" {
+		adminHost = "127.0.0.1"
+	}
+
+	adminPort := os.Getenv("ADMIN_PORT")
+	if adminPort == "" {
+		adminPort = fmt.Sprint(9090)
+	}
+
+	if adminPort == port {
+		return nil, fmt.Errorf("ADMIN_PORT must differ from PORT")
+	}
+
+	siteURL := strings.TrimSuffix(os.Getenv("SITE_URL"), "/")
+	if siteURL == "" {
+		siteURL = "http://localhost:" + port
+	}
+
+	siteTitle := os.Getenv("SITE_TITLE")
+	if siteTitle == "" {
+		siteTitle = "Nexablog"
+	}
+
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Nexablog <no-reply@nexablog.local>"
+	}
+
+	smtpPort, err := intEnv("SMTP_PORT", 587)
+	if err != nil {
+		return nil, err
+	}
+
 	uri := os.Getenv("PG_URI")
 	if uri == "" {
 		return nil, fmt.Errorf("PG_URI value is an empty string: %w", ErrNoValue)
@@ -52,8 +150,54 @@ func New() (*Config, error) {
 		return nil, err
 	}
 
+	schedulerInterval, err := intEnv("SCHEDULER_INTERVAL", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedulerInterval < 1 {
+		return nil, fmt.Errorf("SCHEDULER_INTERVAL must be at least 1 second")
+	}
+
+	accessTTL, err := durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTTL, err := durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	if accessTTL <= 0 || refreshTTL <= accessTTL {
+		return nil, fmt.Errorf("REFRESH_TOKEN_TTL must be longer than a positive ACCESS_TOKEN_TTL")
+	}
+
+	providers, err := oidcProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	shutdownDelay, err := durationEnv("SHUTDOWN_DELAY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	autoMigrate, err := boolEnv("AUTO_MIGRATE", false)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		Port: port,
+		Port:              port,
+		AutoMigrate:       autoMigrate,
+		AdminHost:         adminHost,
+		AdminPort:         adminPort,
+		ShutdownDelay:     shutdownDelay,
+		SiteURL:           siteURL,
+		SiteTitle:         siteTitle,
+		SchedulerInterval: schedulerInterval,
+		OIDC:              providers,
 		DB: struct {
 			Uri string
 			IdleConns,
@@ -67,5 +211,237 @@ func New() (*Config, error) {
 		},
 	}
 
+	if err := loadLockout(cfg); err != nil {
+		return nil, err
+	}
+
+	limits, err := rateLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Log.Format = os.Getenv("LOG_FORMAT")
+	cfg.Log.Level = os.Getenv("LOG_LEVEL")
+
+	sampleRatio, err := floatEnv("TRACING_SAMPLE_RATIO", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if sampleRatio < 0 || sampleRatio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	cfg.Tracing.Exporter = os.Getenv("TRACING_EXPORTER")
+	cfg.Tracing.SampleRatio = sampleRatio
+
+	cfg.RateLimit.Store = os.Getenv("RATE_LIMIT_STORE")
+	cfg.RateLimit.Limits = limits
+
+	cfg.Token.AccessTTL = accessTTL
+	cfg.Token.RefreshTTL = refreshTTL
+
+	cfg.Mail.Driver = os.Getenv("MAILER")
+	cfg.Mail.From = mailFrom
+	cfg.Mail.Dir = os.Getenv("MAIL_DIR")
+	cfg.Mail.SMTPHost = os.Getenv("SMTP_HOST")
+	cfg.Mail.SMTPPort = smtpPort
+	cfg.Mail.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	cfg.Mail.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+
 	return cfg, nil
 }
+
+func loadLockout(cfg *Config) error {
+	var err error
+
+	l := &cfg.Lockout
+
+	if l.AccountBackoffAfter, err = intEnv("LOGIN_BACKOFF_AFTER", 3); err != nil {
+		return err
+	}
+
+	if l.IPBackoffAfter, err = intEnv("LOGIN_IP_BACKOFF_AFTER", 20); err != nil {
+		return err
+	}
+
+	if l.LockAfter, err = intEnv("LOGIN_LOCK_AFTER", 10); err != nil {
+		return err
+	}
+
+	if l.BackoffBase, err = durationEnv("LOGIN_BACKOFF_BASE", time.Second); err != nil {
+		return err
+	}
+
+	if l.BackoffMax, err = durationEnv("LOGIN_BACKOFF_MAX", 5*time.Minute); err != nil {
+		return err
+	}
+
+	if l.LockDuration, err = durationEnv("LOGIN_LOCK_DURATION", 15*time.Minute); err != nil {
+		return err
+	}
+
+	if l.Window, err = durationEnv("LOGIN_FAILURE_WINDOW", time.Hour); err != nil {
+		return err
+	}
+
+	if l.BackoffBase <= 0 || l.BackoffMax < l.BackoffBase {
+		return fmt.Errorf("LOGIN_BACKOFF_MAX must not be shorter than a positive LOGIN_BACKOFF_BASE")
+	}
+
+	if l.LockDuration <= 0 || l.Window <= 0 {
+		return fmt.Errorf("LOGIN_LOCK_DURATION and LOGIN_FAILURE_WINDOW must be positive")
+	}
+
+	return nil
+}
+
+func rateLimits() (map[string]RateLimit, error) {
+	defaults := map[string]string{
+		"default": "120/1m",
+		"tokens":  "10/1m",
+		"oidc":    "20/1m",
+	}
+
+	limits := make(map[string]RateLimit)
+
+	for _, group := range RateLimitGroups {
+		key := "RATE_LIMIT_" + strings.ToUpper(group)
+
+		s := os.Getenv(key)
+		if s == "" {
+			s = defaults[group]
+		}
+
+		if s == "" {
+			continue
+		}
+
+		limit, err := parseRateLimit(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s value %q must look like 60/1m or be off: %w", key, s, err)
+		}
+
+		limits[group] = limit
+	}
+
+	return limits, nil
+}
+
+// parseRateLimit reads a limit written as requests/period, such as 60/1m.
+// off gives a zero limit, which disables limiting for the group.
+func parseRateLimit(s string) (RateLimit, error) {
+	if s == "off" {
+		return RateLimit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, ErrNoValue
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil {
+		return RateLimit{}, err
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil {
+		return RateLimit{}, err
+	}
+
+	if n < 1 || d <= 0 {
+		return RateLimit{}, fmt.Errorf("requests and period must be positive")
+	}
+
+	return RateLimit{Requests: n, Period: d}, nil
+}
+
+func oidcProviders() ([]OIDCProvider, error) {
+	providers := make([]OIDCProvider, 0)
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if !providerName.MatchString(name) {
+			return nil, fmt.Errorf("OIDC provider name %q must match %s", name, providerName)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID must be set: %w", prefix, prefix, ErrNoValue)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+func intEnv(key string, def int) (int, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s value is not an integer: %w", key, err)
+	}
+
+	return i, nil
+}
+
+func boolEnv(key string, def bool) (bool, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("%s value is not a boolean: %w", key, err)
+	}
+
+	return b, nil
+}
+
+func floatEnv(key string, def float64) (float64, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s value is not a number: %w", key, err)
+	}
+
+	return f, nil
+}
+
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s value is not a duration: %w", key, err)
+	}
+
+	return d, nil
+}
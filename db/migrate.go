@@ -0,0 +1,485 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLockID keys the advisory lock held while migrating, so that
+// instances starting together do not run the same migration twice.
+const migrationLockID = 7_301_924
+
+var ErrChecksumMismatch = errors.New("applied migration was modified")
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+	// Modified is set when the migration file no longer matches the one
+	// that was applied.
+	Modified bool
+	// Unknown is set for versions applied to the database that are not
+	// shipped with the binary.
+	Unknown bool
+}
+
+// LoadMigrations reads the embedded migrations in version order. Every
+// version must come with both an up and a down file.
+func LoadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, file := range files {
+		base := path.Base(file)
+
+		prefix, rest, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_name.up.sql", base)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", base, prefix)
+		}
+
+		b, err := migrations.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var name, direction string
+
+		switch {
+		case strings.HasSuffix(rest, ".up.sql"):
+			name, direction = strings.TrimSuffix(rest, ".up.sql"), "up"
+		case strings.HasSuffix(rest, ".down.sql"):
+			name, direction = strings.TrimSuffix(rest, ".down.sql"), "down"
+		default:
+			return nil, fmt.Errorf("migration %s: must end in .up.sql or .down.sql", base)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d: up and down files are named differently", version)
+		}
+
+		if direction == "up" {
+			sum := sha256.Sum256(b)
+			m.Up = string(b)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+
+	for _, m := range byVersion {
+		if m.Checksum == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: both up and down files are required", m.Version, m.Name)
+		}
+
+		list = append(list, *m)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list, nil
+}
+
+// LatestVersion is the version of the newest migration shipped with the
+// binary.
+func LatestVersion() (int, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(list) == 0 {
+		return 0, nil
+	}
+
+	return list[len(list)-1].Version, nil
+}
+
+// SchemaVersion is the newest migration applied to the database.
+func (db *DB) SchemaVersion(ctx context.Context) (int, error) {
+	q := `SELECT COALESCE(MAX(version), 0) FROM schema_versions;`
+
+	var version int
+
+	err := db.QueryRowContext(ctx, q).Scan(&version)
+
+	return version, err
+}
+
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+}
+
+func (db *DB) Migrator() (*Migrator, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db, list}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the newest applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+
+			return m.apply(ctx, conn, mig, false)
+		}
+
+		return nil
+	})
+}
+
+// To applies the pending migrations up to version and reverts the applied
+// ones above it, newest first. Version 0 reverts everything.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version != 0 && !m.has(version) {
+		return fmt.Errorf("there is no migration %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+
+			if _, ok := applied[mig.Version]; !ok || mig.Version <= version {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, mig, false); err != nil {
+				return err
+			}
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok || mig.Version > version {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status lists the shipped and applied migrations. It only reads, so it
+// neither waits for a running migration nor creates schema_versions, and a
+// database that was never migrated shows every migration as pending.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var exists bool
+
+	q := `SELECT to_regclass('schema_versions') IS NOT NULL;`
+
+	if err := m.db.QueryRowContext(ctx, q).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]appliedMigration)
+
+	if exists {
+		var err error
+
+		applied, err = m.applied(ctx, m.db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	list := make([]MigrationStatus, 0, len(m.migrations))
+
+	for _, mig := range m.migrations {
+		status := MigrationStatus{Migration: mig}
+
+		if a, ok := applied[mig.Version]; ok {
+			appliedAt := a.appliedAt
+			status.AppliedAt = &appliedAt
+			status.Modified = a.checksum != mig.Checksum
+		}
+
+		list = append(list, status)
+	}
+
+	for version, a := range applied {
+		if m.has(version) {
+			continue
+		}
+
+		appliedAt := a.appliedAt
+
+		list = append(list, MigrationStatus{
+			Migration: Migration{Version: version, Name: a.name, Checksum: a.checksum},
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list, nil
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// withLock runs f on a single connection holding the migration lock, with
+// the migrations applied so far.
+func (m *Migrator) withLock(
+	ctx context.Context,
+	f func(*sql.Conn, map[int]appliedMigration) error,
+) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationLockID); err != nil {
+		return err
+	}
+
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationLockID)
+	}()
+
+	q := `
+  CREATE TABLE IF NOT EXISTS schema_versions (
+    version INT PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+  );
+  `
+
+	if _, err := conn.ExecContext(ctx, q); err != nil {
+		return err
+	}
+
+	if err := m.baseline(ctx, conn); err != nil {
+		return err
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return f(conn, applied)
+}
+
+// baseline adopts a database migrated by an external tool that records its
+// version in schema_migrations, marking every migration up to that version
+// as applied.
+func (m *Migrator) baseline(ctx context.Context, conn *sql.Conn) error {
+	q := `
+  SELECT to_regclass('schema_migrations') IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM schema_versions);
+  `
+
+	var adopt bool
+
+	if err := conn.QueryRowContext(ctx, q).Scan(&adopt); err != nil || !adopt {
+		return err
+	}
+
+	var (
+		version int
+		dirty   bool
+	)
+
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1;`).
+		Scan(&version, &dirty)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("schema_migrations is dirty at version %d, fix it by hand first", version)
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+
+		q := `INSERT INTO schema_versions (version, name, checksum) VALUES ($1, $2, $3);`
+
+		if _, err := conn.ExecContext(ctx, q, mig.Version, mig.Name, mig.Checksum); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type querier interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}
+
+func (m *Migrator) applied(ctx context.Context, db querier) (map[int]appliedMigration, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_versions;`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	applied := make(map[int]appliedMigration)
+
+	for rows.Next() {
+		var (
+			version int
+			a       appliedMigration
+		)
+
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	for _, mig := range m.migrations {
+		if a, ok := applied[mig.Version]; ok && a.checksum != mig.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+
+	return nil
+}
+
+// apply runs a migration and records it in the same transaction, so that a
+// failed migration leaves no trace.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	script, record := mig.Down, `DELETE FROM schema_versions WHERE version = $1;`
+	args := []any{mig.Version}
+
+	if up {
+		script, record = mig.Up, `INSERT INTO schema_versions (version, name, checksum) VALUES ($1, $2, $3);`
+		args = append(args, mig.Name, mig.Checksum)
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	msg := "reverted migration"
+	if up {
+		msg = "applied migration"
+	}
+
+	slog.InfoContext(ctx, msg, "version", mig.Version, "name", mig.Name)
+
+	return nil
+}
+
+func (m *Migrator) has(version int) bool {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,2 +1,2 @@
-DROP TABLE IF EXISTS permissions;
 DROP TABLE IF EXISTS users_permissions;
+DROP TABLE IF EXISTS permissions;
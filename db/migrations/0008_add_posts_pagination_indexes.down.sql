@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS posts_author_id_idx;
+DROP INDEX IF EXISTS posts_created_at_post_id_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS posts_created_at_post_id_idx ON posts(created_at, post_id);
+CREATE INDEX IF NOT EXISTS posts_author_id_idx ON posts(author_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS posts_scheduled_idx;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS published_at;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS status;
@@ -0,0 +1,10 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS status VARCHAR NOT NULL DEFAULT 'published'
+  CHECK(status IN ('draft', 'published', 'scheduled', 'archived'));
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS published_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE posts SET published_at = created_at WHERE published_at IS NULL;
+
+ALTER TABLE posts ALTER COLUMN status SET DEFAULT 'draft';
+
+CREATE INDEX IF NOT EXISTS posts_scheduled_idx ON posts(published_at) WHERE status = 'scheduled';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+  post_id INT NOT NULL,
+  version INT NOT NULL,
+  title VARCHAR NOT NULL,
+  body TEXT NOT NULL,
+  editor_id INT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY(post_id, version),
+  CONSTRAINT post_revisions_posts_fk FOREIGN KEY(post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+  CONSTRAINT post_revisions_users_fk FOREIGN KEY(editor_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+INSERT INTO post_revisions (post_id, version, title, body, editor_id, created_at)
+SELECT post_id, version, title, body, author_id, COALESCE(created_at, now()) FROM posts
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE code = 'tags:manage';
+
+DROP TABLE IF EXISTS posts_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS tags (
+  tag_id INT generated always as identity,
+  name VARCHAR NOT NULL UNIQUE,
+  PRIMARY KEY(tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS posts_tags (
+  post_id INT NOT NULL,
+  tag_id INT NOT NULL,
+  PRIMARY KEY(post_id, tag_id),
+  CONSTRAINT posts_tags_posts_fk FOREIGN KEY(post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+  CONSTRAINT posts_tags_tags_fk FOREIGN KEY(tag_id) REFERENCES tags(tag_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS posts_tags_tag_id_idx ON posts_tags(tag_id);
+
+INSERT INTO permissions (code) VALUES ('tags:manage');
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code = 'comments:write';
+
+DROP TABLE IF EXISTS comments;
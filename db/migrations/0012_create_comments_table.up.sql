@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS comments (
+  comment_id INT generated always as identity,
+  post_id INT NOT NULL,
+  parent_id INT,
+  author_id INT NOT NULL,
+  body TEXT NOT NULL,
+  version INT NOT NULL DEFAULT 1,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP WITH TIME ZONE,
+  deleted_at TIMESTAMP WITH TIME ZONE,
+  PRIMARY KEY(comment_id),
+  CONSTRAINT comments_posts_fk FOREIGN KEY(post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+  CONSTRAINT comments_parent_fk FOREIGN KEY(parent_id) REFERENCES comments(comment_id) ON DELETE CASCADE,
+  CONSTRAINT comments_users_fk FOREIGN KEY(author_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS comments_post_id_parent_id_idx ON comments(post_id, parent_id, comment_id);
+CREATE INDEX IF NOT EXISTS comments_parent_id_idx ON comments(parent_id);
+
+INSERT INTO permissions (code) VALUES ('comments:write');
+
+INSERT INTO users_permissions (permission_id, user_id)
+SELECT p.permission_id, u.user_id FROM permissions p, users u
+WHERE p.code = 'comments:write'
+ON CONFLICT DO NOTHING;
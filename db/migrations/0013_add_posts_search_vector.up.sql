@@ -0,0 +1,7 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+UPDATE posts SET search_vector = 
+  setweight(to_tsvector('english', title), 'A') ||
+  setweight(to_tsvector('english', body), 'B');
+
+CREATE INDEX IF NOT EXISTS posts_search_vector_idx ON posts USING GIN(search_vector);
@@ -0,0 +1,70 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS slug VARCHAR;
+
+ALTER TABLE posts ADD CONSTRAINT posts_slug_key UNIQUE(slug);
+
+DO $$
+DECLARE
+  p RECORD;
+  r RECORD;
+  base VARCHAR;
+  suffix VARCHAR;
+  candidate VARCHAR;
+  n INT;
+BEGIN
+  FOR p IN SELECT post_id, title FROM posts WHERE slug IS NULL ORDER BY post_id LOOP
+    base := lower(regexp_replace(
+      normalize(lower(p.title), NFKD),
+      '[\u0300-\u036f\u1ab0-\u1aff\u1dc0-\u1dff\u20d0-\u20ff\ufe20-\ufe2f]', '', 'g'
+    ));
+
+    FOR r IN SELECT * FROM (VALUES
+      ('ß', 'ss'), ('æ', 'ae'), ('œ', 'oe'), ('ø', 'o'), ('đ', 'd'), ('ð', 'd'), ('ł', 'l'), ('þ', 'th'),
+      ('ı', 'i'), ('ŋ', 'ng'), ('&', 'and'), ('а', 'a'), ('б', 'b'), ('в', 'v'), ('г', 'g'), ('д', 'd'),
+      ('е', 'e'), ('ё', 'yo'), ('ж', 'zh'), ('з', 'z'), ('и', 'i'), ('й', 'y'), ('к', 'k'), ('л', 'l'),
+      ('м', 'm'), ('н', 'n'), ('о', 'o'), ('п', 'p'), ('р', 'r'), ('с', 's'), ('т', 't'), ('у', 'u'),
+      ('ф', 'f'), ('х', 'kh'), ('ц', 'ts'), ('ч', 'ch'), ('ш', 'sh'), ('щ', 'shch'), ('ъ', ''), ('ы', 'y'),
+      ('ь', ''), ('э', 'e'), ('ю', 'yu'), ('я', 'ya'), ('є', 'ye'), ('і', 'i'), ('ї', 'yi'), ('ґ', 'g'),
+      ('α', 'a'), ('β', 'v'), ('γ', 'g'), ('δ', 'd'), ('ε', 'e'), ('ζ', 'z'), ('η', 'i'), ('θ', 'th'),
+      ('ι', 'i'), ('κ', 'k'), ('λ', 'l'), ('μ', 'm'), ('ν', 'n'), ('ξ', 'x'), ('ο', 'o'), ('π', 'p'),
+      ('ρ', 'r'), ('σ', 's'), ('ς', 's'), ('τ', 't'), ('υ', 'y'), ('φ', 'f'), ('χ', 'ch'), ('ψ', 'ps'),
+      ('ω', 'o')
+    ) AS t(c, rep) LOOP
+      base := replace(base, r.c, r.rep);
+    END LOOP;
+
+    base := trim(BOTH '-' FROM regexp_replace(base, '[^a-z0-9]+', '-', 'g'));
+
+    IF length(base) > 80 THEN
+      base := trim(BOTH '-' FROM regexp_replace(left(base, 80), '-[^-]*$', ''));
+    END IF;
+
+    base := COALESCE(NULLIF(base, ''), 'post');
+    candidate := base;
+    n := 2;
+
+    WHILE EXISTS (SELECT 1 FROM posts WHERE slug = candidate) LOOP
+      suffix := '-' || n;
+      candidate := base;
+
+      IF length(base) > 80 - length(suffix) THEN
+        candidate := trim(BOTH '-' FROM regexp_replace(left(base, 80 - length(suffix)), '-[^-]*$', ''));
+      END IF;
+
+      candidate := candidate || suffix;
+      n := n + 1;
+    END LOOP;
+
+    UPDATE posts SET slug = candidate WHERE post_id = p.post_id;
+  END LOOP;
+END;
+$$;
+
+ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS post_slugs (
+  slug VARCHAR NOT NULL,
+  post_id INT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY(slug),
+  CONSTRAINT post_slugs_posts_fk FOREIGN KEY(post_id) REFERENCES posts(post_id) ON DELETE CASCADE
+);
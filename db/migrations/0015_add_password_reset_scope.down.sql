@@ -0,0 +1,5 @@
+DELETE FROM tokens WHERE scope = 'password-reset';
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_scope_check;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_scope_check CHECK(scope = 'authentication');
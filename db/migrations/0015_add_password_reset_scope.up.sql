@@ -0,0 +1,4 @@
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_scope_check;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_scope_check 
+  CHECK(scope IN ('authentication', 'password-reset'));
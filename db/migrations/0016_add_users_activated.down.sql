@@ -0,0 +1,8 @@
+DELETE FROM tokens WHERE scope = 'activation';
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_scope_check;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_scope_check 
+  CHECK(scope IN ('authentication', 'password-reset'));
+
+ALTER TABLE users DROP COLUMN IF EXISTS activated;
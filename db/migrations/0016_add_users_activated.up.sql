@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS activated BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE users SET activated = true;
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_scope_check;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_scope_check 
+  CHECK(scope IN ('authentication', 'password-reset', 'activation'));
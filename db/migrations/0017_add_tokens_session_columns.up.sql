@@ -0,0 +1,9 @@
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS token_id INT generated always as identity;
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS user_agent VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS ip VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_pkey PRIMARY KEY(token_id);
+
+CREATE INDEX IF NOT EXISTS tokens_user_id_scope_idx ON tokens(user_id, scope);
@@ -0,0 +1,11 @@
+DELETE FROM tokens WHERE scope = 'refresh';
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_scope_check;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_scope_check 
+  CHECK(scope IN ('authentication', 'password-reset', 'activation'));
+
+DROP INDEX IF EXISTS tokens_family_idx;
+
+ALTER TABLE tokens DROP COLUMN IF EXISTS used_at;
+ALTER TABLE tokens DROP COLUMN IF EXISTS family;
@@ -0,0 +1,13 @@
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS family VARCHAR;
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE tokens SET family = 'legacy-' || token_id WHERE family IS NULL;
+
+ALTER TABLE tokens ALTER COLUMN family SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS tokens_family_idx ON tokens(family);
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_scope_check;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_scope_check 
+  CHECK(scope IN ('authentication', 'password-reset', 'activation', 'refresh'));
@@ -0,0 +1,15 @@
+DELETE FROM tokens WHERE scope = 'personal';
+
+DROP INDEX IF EXISTS tokens_personal_name_idx;
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_expiry_check;
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_scope_check;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_scope_check 
+  CHECK(scope IN ('authentication', 'password-reset', 'activation', 'refresh'));
+
+ALTER TABLE tokens ALTER COLUMN expires_at SET NOT NULL;
+
+ALTER TABLE tokens DROP COLUMN IF EXISTS permissions;
+ALTER TABLE tokens DROP COLUMN IF EXISTS name;
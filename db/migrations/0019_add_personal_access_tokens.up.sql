@@ -0,0 +1,15 @@
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS name VARCHAR;
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS permissions VARCHAR[];
+
+ALTER TABLE tokens ALTER COLUMN expires_at DROP NOT NULL;
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_scope_check;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_scope_check 
+  CHECK(scope IN ('authentication', 'password-reset', 'activation', 'refresh', 'personal'));
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_expiry_check
+  CHECK(scope = 'personal' OR expires_at IS NOT NULL);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tokens_personal_name_idx 
+  ON tokens(user_id, name) WHERE scope = 'personal';
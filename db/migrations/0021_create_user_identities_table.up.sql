@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+  provider VARCHAR NOT NULL,
+  subject VARCHAR NOT NULL,
+  user_id INT NOT NULL,
+  email VARCHAR NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY(provider, subject),
+  CONSTRAINT user_identities_users_fk 
+    FOREIGN KEY(user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_states (
+  hash BYTEA NOT NULL,
+  provider VARCHAR NOT NULL,
+  nonce VARCHAR NOT NULL,
+  code_verifier VARCHAR NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  PRIMARY KEY(hash)
+);
@@ -0,0 +1,12 @@
+INSERT INTO users_permissions (permission_id, user_id)
+SELECT DISTINCT rp.permission_id, ur.user_id 
+FROM users_roles ur INNER JOIN roles_permissions rp USING(role_id)
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS users_roles;
+DROP TABLE IF EXISTS roles_permissions;
+DROP TABLE IF EXISTS roles;
+
+DELETE FROM permissions WHERE code = 'users:manage';
+
+ALTER TABLE permissions DROP CONSTRAINT IF EXISTS permissions_code_key;
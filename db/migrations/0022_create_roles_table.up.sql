@@ -0,0 +1,58 @@
+ALTER TABLE permissions ADD CONSTRAINT permissions_code_key UNIQUE(code);
+
+INSERT INTO permissions (code) VALUES ('users:manage') ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS roles (
+  role_id INT generated always as identity,
+  name VARCHAR NOT NULL UNIQUE,
+  PRIMARY KEY(role_id)
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+  role_id INT,
+  permission_id INT,
+  PRIMARY KEY(role_id, permission_id),
+  CONSTRAINT roles_permissions_roles_fk 
+    FOREIGN KEY(role_id) REFERENCES roles(role_id) ON DELETE CASCADE,
+  CONSTRAINT roles_permissions_permissions_fk 
+    FOREIGN KEY(permission_id) REFERENCES permissions(permission_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS users_roles (
+  role_id INT,
+  user_id INT,
+  PRIMARY KEY(role_id, user_id),
+  CONSTRAINT users_roles_roles_fk 
+    FOREIGN KEY(role_id) REFERENCES roles(role_id) ON DELETE CASCADE,
+  CONSTRAINT users_roles_users_fk 
+    FOREIGN KEY(user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS users_roles_user_id_idx ON users_roles(user_id);
+
+INSERT INTO roles (name) VALUES ('reader'), ('author'), ('editor'), ('admin');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id FROM roles r, permissions p
+WHERE (r.name = 'reader' AND p.code IN ('posts:read'))
+OR (r.name = 'author' AND p.code IN ('posts:read', 'posts:write', 'comments:write'))
+OR (r.name = 'editor' AND p.code IN ('posts:read', 'posts:write', 'comments:write', 'tags:manage'))
+OR r.name = 'admin';
+
+INSERT INTO users_roles (role_id, user_id)
+SELECT DISTINCT ON (up.user_id) r.role_id, up.user_id
+FROM users_permissions up, roles r
+WHERE r.name IN ('reader', 'author', 'editor')
+AND NOT EXISTS (
+  SELECT 1 FROM roles_permissions rp
+  WHERE rp.role_id = r.role_id AND rp.permission_id NOT IN (
+    SELECT permission_id FROM users_permissions WHERE user_id = up.user_id
+  )
+)
+ORDER BY up.user_id, (SELECT count(*) FROM roles_permissions WHERE role_id = r.role_id) DESC
+ON CONFLICT DO NOTHING;
+
+DELETE FROM users_permissions up
+USING users_roles ur, roles_permissions rp
+WHERE ur.user_id = up.user_id AND rp.role_id = ur.role_id 
+AND rp.permission_id = up.permission_id;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_moderations;
+
+DELETE FROM permissions WHERE code = 'posts:moderate';
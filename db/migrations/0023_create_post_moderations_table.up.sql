@@ -0,0 +1,23 @@
+INSERT INTO permissions (code) VALUES ('posts:moderate') ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id FROM roles r, permissions p
+WHERE r.name IN ('editor', 'admin') AND p.code = 'posts:moderate'
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS post_moderations (
+  moderation_id INT generated always as identity,
+  post_id INT NOT NULL,
+  author_id INT,
+  moderator_id INT,
+  action VARCHAR NOT NULL CHECK(action IN ('update', 'delete')),
+  reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY(moderation_id),
+  CONSTRAINT post_moderations_authors_fk 
+    FOREIGN KEY(author_id) REFERENCES users(user_id) ON DELETE SET NULL,
+  CONSTRAINT post_moderations_moderators_fk 
+    FOREIGN KEY(moderator_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS post_moderations_post_id_idx ON post_moderations(post_id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  audit_id BIGINT generated always as identity,
+  action VARCHAR NOT NULL,
+  actor_id INT,
+  target_type VARCHAR NOT NULL DEFAULT '',
+  target_id VARCHAR NOT NULL DEFAULT '',
+  ip VARCHAR NOT NULL DEFAULT '',
+  user_agent VARCHAR NOT NULL DEFAULT '',
+  before JSONB,
+  after JSONB,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY(audit_id)
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log(action, audit_id);
+CREATE INDEX IF NOT EXISTS audit_log_actor_id_idx ON audit_log(actor_id, audit_id);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log(target_type, target_id, audit_id);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log(created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_throttles (
+  key VARCHAR PRIMARY KEY,
+  failures INT NOT NULL DEFAULT 0,
+  blocked_until TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS login_throttles_last_failed_at_idx ON login_throttles(last_failed_at);
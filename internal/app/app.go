@@ -4,25 +4,49 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"nexablog/config"
 	"nexablog/db"
+	"nexablog/internal/mailer"
+	"nexablog/internal/policy"
+	"nexablog/internal/ratelimit"
+	"nexablog/internal/repository/audit"
+	"nexablog/internal/repository/comment"
+	"nexablog/internal/repository/identity"
+	"nexablog/internal/repository/lockout"
+	"nexablog/internal/repository/moderation"
 	"nexablog/internal/repository/permission"
 	"nexablog/internal/repository/post"
+	"nexablog/internal/repository/revision"
+	"nexablog/internal/repository/tag"
 	"nexablog/internal/repository/token"
 	"nexablog/internal/repository/user"
+	"nexablog/internal/telemetry"
+	"nexablog/pkg/oidc"
 )
 
 type App struct {
 	cfg      *config.Config
 	database *db.DB
 	mux      *chi.Mux
+	adminMux *chi.Mux
 	repos    *repos
+	mailer   mailer.Mailer
+	oidc     map[string]*oidc.Provider
+	policy   *policy.Policy
+	limiter  ratelimit.Store
+	// latestVersion is the newest migration shipped with the binary, which
+	// the database must have reached for the app to be ready.
+	latestVersion int
+
+	shuttingDown atomic.Bool
 }
 
 type repos struct {
@@ -30,32 +54,79 @@ type repos struct {
 	token      token.Repo
 	post       post.Repo
 	permission permission.Repo
+	revision   revision.Repo
+	tag        tag.Repo
+	comment    comment.Repo
+	identity   identity.Repo
+	moderation moderation.Repo
+	audit      audit.Repo
+	lockout    lockout.Repo
 }
 
-func New(cfg *config.Config, database *db.DB) *App {
+func New(cfg *config.Config, database *db.DB, m mailer.Mailer, limiter ratelimit.Store) (*App, error) {
+	latestVersion, err := db.LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+
 	app := &App{
 		cfg:      cfg,
 		database: database,
 		mux:      chi.NewRouter(),
+		adminMux: chi.NewRouter(),
+		mailer:   m,
+		limiter:  limiter,
+
+		latestVersion: latestVersion,
 	}
 
 	app.loadRepos()
+	app.loadProviders()
+
+	app.policy = policy.New(app.repos.permission)
+
 	app.loadRoutes()
+	app.loadAdminServerRoutes()
+
+	telemetry.ObserveDB(database.DB)
 
-	return app
+	return app, nil
 }
 
 func (app *App) loadRepos() {
+	dbtx := telemetry.TraceDB(app.database)
+
 	r := &repos{
-		user:       user.NewRepo(app.database),
-		token:      token.NewRepo(app.database),
-		post:       post.NewRepo(app.database),
-		permission: permission.NewRepo(app.database),
+		user:       user.NewRepo(dbtx),
+		token:      token.NewRepo(dbtx),
+		post:       post.NewRepo(dbtx),
+		permission: permission.NewRepo(dbtx),
+		revision:   revision.NewRepo(dbtx),
+		tag:        tag.NewRepo(dbtx),
+		comment:    comment.NewRepo(dbtx),
+		identity:   identity.NewRepo(dbtx),
+		moderation: moderation.NewRepo(dbtx),
+		audit:      audit.NewRepo(dbtx),
+		lockout:    lockout.NewRepo(dbtx),
 	}
 
 	app.repos = r
 }
 
+func (app *App) loadProviders() {
+	app.oidc = make(map[string]*oidc.Provider)
+
+	for _, p := range app.cfg.OIDC {
+		app.oidc[p.Name] = oidc.New(oidc.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  app.cfg.SiteURL + "/api/oidc/" + p.Name + "/callback",
+			Scopes:       p.Scopes,
+		})
+	}
+}
+
 func (app *App) StartAndRun(ctx context.Context) error {
 	errch := make(chan error)
 
@@ -64,10 +135,10 @@ func (app *App) StartAndRun(ctx context.Context) error {
 	}
 
 	defer func() {
-		log.Println("database is closing")
+		slog.Info("database is closing")
 		err := app.database.Close()
 		if err != nil {
-			log.Println(err)
+			slog.Error("could not close database", "error", err)
 		}
 	}()
 
@@ -78,7 +149,23 @@ func (app *App) StartAndRun(ctx context.Context) error {
 		Handler:      app.mux,
 	}
 
-	log.Println("app is starting")
+	adminServer := &http.Server{
+		Addr:         net.JoinHostPort(app.cfg.AdminHost, app.cfg.AdminPort),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		Handler:      app.adminMux,
+	}
+
+	slog.Info("app is starting", "port", app.cfg.Port, "admin_addr", adminServer.Addr)
+
+	go app.runScheduler(ctx)
+
+	go func() {
+		err := adminServer.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			errch <- fmt.Errorf("admin server: %w", err)
+		}
+	}()
 
 	go func() {
 		err := server.ListenAndServe()
@@ -92,10 +179,20 @@ func (app *App) StartAndRun(ctx context.Context) error {
 	case err := <-errch:
 		return err
 	case <-ctx.Done():
+		app.shuttingDown.Store(true)
+
+		slog.Info("app is shutting down", "delay", app.cfg.ShutdownDelay)
+
+		// Give the orchestrator time to see readyz fail and stop routing
+		// traffic here before the server stops accepting connections.
+		time.Sleep(app.cfg.ShutdownDelay)
+
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		log.Println("app is shutting down")
+		if err := adminServer.Shutdown(ctx); err != nil {
+			slog.Error("could not shut down admin server", "error", err)
+		}
 
 		err := server.Shutdown(ctx)
 		if !errors.Is(err, http.ErrServerClosed) {
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"nexablog/internal/utils"
+	"nexablog/pkg/lib"
+)
+
+const readinessTimeout = 2 * time.Second
+
+func (app *App) healthz(w http.ResponseWriter, r *http.Request) {
+	_ = utils.WriteJson(w, http.StatusOK, lib.H[string]{
+		"status": "ok",
+	})
+}
+
+// readyz reports whether the app should receive traffic: the database is
+// reachable, its schema is at least as new as the migrations shipped with the
+// binary, so that older instances stay ready during a rolling deploy, and
+// shutdown has not begun.
+func (app *App) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := lib.H[string]{
+		"shutdown":   "ok",
+		"database":   "ok",
+		"migrations": "ok",
+	}
+
+	ready := true
+
+	fail := func(check, reason string) {
+		checks[check] = reason
+		ready = false
+	}
+
+	if app.shuttingDown.Load() {
+		fail("shutdown", "shutting down")
+	}
+
+	if err := app.database.PingDB(ctx); err != nil {
+		fail("database", "unreachable")
+		fail("migrations", "unknown")
+	} else if reason := app.checkMigrations(ctx); reason != "" {
+		fail("migrations", reason)
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+
+	_ = utils.WriteJson(w, code, lib.H[any]{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+func (app *App) checkMigrations(ctx context.Context) string {
+	version, err := app.database.SchemaVersion(ctx)
+
+	switch {
+	case err != nil:
+		return "unknown"
+	case version < app.latestVersion:
+		return fmt.Sprintf("at version %d, expected %d", version, app.latestVersion)
+	}
+
+	return ""
+}
@@ -2,24 +2,190 @@ package app
 
 import (
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
 	"regexp"
+	"runtime/debug"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"nexablog/internal/models"
+	"nexablog/internal/ratelimit"
 	"nexablog/internal/repository"
+	"nexablog/internal/telemetry"
 	"nexablog/internal/utils"
 	"nexablog/pkg/lib"
 )
 
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestID reuses a well-formed X-Request-ID sent by a proxy in front of
+// the app and generates one otherwise.
+func (app *App) requestID(n http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+
+		if !requestIDPattern.MatchString(id) {
+			var err error
+
+			if id, err = utils.GenRandString(10); err != nil {
+				slog.ErrorContext(r.Context(), "could not generate request id", "error", err)
+			}
+		}
+
+		w.Header().Set("X-Request-ID", id)
+
+		r = utils.SetRequestInfo(r, &utils.RequestInfo{ID: id})
+		n.ServeHTTP(w, r)
+	})
+}
+
+// traceRequest continues the trace of a W3C traceparent header, if any, and
+// names the span after the route pattern once it is known.
+func (app *App) traceRequest(n http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := telemetry.Start(
+			ctx,
+			r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("client.address", utils.ClientIP(r)),
+				attribute.String("user_agent.original", utils.UserAgent(r)),
+			),
+		)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		r = r.WithContext(ctx)
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if route := routePattern(r); route != "" {
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(attribute.String("http.route", route))
+			}
+
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+			if info := utils.GetRequestInfo(ctx); info != nil {
+				span.SetAttributes(attribute.String("request.id", info.ID))
+
+				if info.UserID != 0 {
+					span.SetAttributes(attribute.Int("enduser.id", info.UserID))
+				}
+			}
+
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			span.End()
+		}()
+
+		n.ServeHTTP(ww, r)
+	})
+}
+
+func (app *App) logRequest(n http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			slog.LogAttrs(
+				r.Context(),
+				level,
+				"request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", routePattern(r)),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("ip", utils.ClientIP(r)),
+			)
+		}()
+
+		n.ServeHTTP(ww, r)
+	})
+}
+
+// instrument records request metrics by route pattern rather than path, so
+// that IDs in URLs do not create a series each.
+func (app *App) instrument(n http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		telemetry.HTTPInFlight.Inc()
+
+		defer func() {
+			telemetry.HTTPInFlight.Dec()
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			route := routePattern(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			telemetry.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+			telemetry.HTTPDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		}()
+
+		n.ServeHTTP(ww, r)
+	})
+}
+
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+
+	return ""
+}
+
 func (app *App) recoverer(n http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
-			if r := recover(); r != nil {
-				log.Println(r)
+			if rec := recover(); rec != nil {
+				slog.ErrorContext(
+					r.Context(),
+					"panic",
+					"error", rec,
+					"stack", string(debug.Stack()),
+				)
 				w.WriteHeader(http.StatusInternalServerError)
 			}
 		}()
@@ -28,7 +194,23 @@ func (app *App) recoverer(n http.Handler) http.Handler {
 	})
 }
 
+// authenticate resolves the bearer token of a request. Requests with a token
+// that is rejected are limited by client IP like anonymous ones, since they
+// never reach the limits of their route group.
 func (app *App) authenticate(n http.Handler) http.Handler {
+	take := app.rateLimiter("default")
+
+	unauthorized := func(w http.ResponseWriter, r *http.Request) {
+		if take != nil && !take(w, r, "default:ip:"+utils.ClientIP(r)) {
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		_ = utils.WriteJson(w, http.StatusUnauthorized, lib.H[string]{
+			"detail": "unauthorized",
+		})
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Vary", "Authentication")
 
@@ -43,26 +225,21 @@ func (app *App) authenticate(n http.Handler) http.Handler {
 		}
 
 		if !pt.Match([]byte(authheader)) {
-			w.Header().Set("WWW-Authenticate", "Bearer")
-			_ = utils.WriteJson(w, http.StatusUnauthorized, lib.H[string]{
-				"detail": "unauthorized",
-			})
+			unauthorized(w, r)
 			return
 		}
 
 		token := pt.FindStringSubmatch(authheader)[1]
 
-		user, err := app.repos.user.FindUserByToken(
-			r.Context(),
-			token,
-			models.ScopeAuthentication,
-		)
+		scope := models.ScopeAuthentication
+		if strings.HasPrefix(token, models.PersonalTokenPrefix) {
+			scope = models.ScopePersonal
+		}
+
+		user, err := app.repos.user.FindUserByToken(r.Context(), token, scope)
 
 		if errors.Is(err, repository.ErrResourceNotFound) {
-			w.Header().Set("WWW-Authenticate", "Bearer")
-			_ = utils.WriteJson(w, http.StatusUnauthorized, lib.H[string]{
-				"detail": "unauthorized",
-			})
+			unauthorized(w, r)
 			return
 		}
 
@@ -73,7 +250,26 @@ func (app *App) authenticate(n http.Handler) http.Handler {
 			return
 		}
 
+		hash := utils.HashRandString(token)
+
+		if scope == models.ScopePersonal {
+			codes, err := app.repos.token.FindTokenPermissions(r.Context(), hash)
+			if err != nil {
+				_ = utils.WriteJson(w, http.StatusInternalServerError, lib.H[string]{
+					"detail": "internal server error",
+				})
+				return
+			}
+
+			r = utils.SetTokenPermissions(r, codes)
+		}
+
+		if err := app.repos.token.TouchToken(r.Context(), hash); err != nil {
+			slog.ErrorContext(r.Context(), "could not touch token", "error", err)
+		}
+
 		r = utils.SetUser(r, &user)
+		r = utils.SetTokenHash(r, hash)
 		n.ServeHTTP(w, r)
 	})
 }
@@ -96,12 +292,7 @@ func (app *App) requireAuth(n http.Handler) http.Handler {
 func (app *App) requirePermission(code string) func(http.Handler) http.Handler {
 	return func(n http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			user := utils.GetUser(r)
-
-			permissions, err := app.repos.permission.GetUserPermission(
-				r.Context(),
-				user.UserID,
-			)
+			allowed, err := app.policy.Can(r, code)
 			if err != nil {
 				_ = utils.WriteJson(w, http.StatusInternalServerError, lib.H[string]{
 					"detail": "internal server error",
@@ -109,7 +300,7 @@ func (app *App) requirePermission(code string) func(http.Handler) http.Handler {
 				return
 			}
 
-			if !permissions.Include(code) {
+			if !allowed {
 				_ = utils.WriteJson(w, http.StatusForbidden, lib.H[string]{
 					"detail": "not allowed",
 				})
@@ -135,3 +326,83 @@ func (app *App) disallowInvalidPostID(n http.Handler) http.Handler {
 		n.ServeHTTP(w, r)
 	})
 }
+
+func (app *App) disallowPersonalToken(n http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if utils.GetTokenPermissions(r) != nil {
+			_ = utils.WriteJson(w, http.StatusForbidden, lib.H[string]{
+				"detail": "personal access tokens cannot manage credentials",
+			})
+			return
+		}
+
+		n.ServeHTTP(w, r)
+	})
+}
+
+// rateLimit limits the requests to a route group per user, or per client IP
+// for anonymous requests, using the group's limit or the default one.
+func (app *App) rateLimit(group string) func(http.Handler) http.Handler {
+	take := app.rateLimiter(group)
+
+	return func(n http.Handler) http.Handler {
+		if take == nil {
+			return n
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := group + ":ip:" + utils.ClientIP(r)
+			if user := utils.GetUser(r); !user.IsAnonymousUser() {
+				key = group + ":user:" + strconv.Itoa(user.UserID)
+			}
+
+			if take(w, r, key) {
+				n.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// rateLimiter returns a function that takes a request from the bucket under key
+// in the limit of group, answering 429 and reporting false when the bucket
+// is empty. It returns nil when the group is not limited.
+func (app *App) rateLimiter(group string) func(http.ResponseWriter, *http.Request, string) bool {
+	cfg, ok := app.cfg.RateLimit.Limits[group]
+	if !ok {
+		cfg, ok = app.cfg.RateLimit.Limits["default"]
+	}
+
+	if !ok || cfg.Requests == 0 {
+		return nil
+	}
+
+	limit := ratelimit.Limit{Requests: cfg.Requests, Period: cfg.Period}
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Period.Seconds()))
+
+	return func(w http.ResponseWriter, r *http.Request, key string) bool {
+		result, err := app.limiter.Take(r.Context(), key, limit)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "could not take from rate limit bucket", "error", err)
+			return true
+		}
+
+		w.Header().Set("RateLimit-Policy", policy)
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+			_ = utils.WriteJson(w, http.StatusTooManyRequests, lib.H[string]{
+				"detail": "rate limit exceeded",
+			})
+			return false
+		}
+
+		return true
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -2,17 +2,26 @@ package app
 
 import (
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 
 	"nexablog/internal/handlers"
+	"nexablog/internal/services/audit"
+	"nexablog/internal/services/comment"
+	"nexablog/internal/services/identity"
+	"nexablog/internal/services/lockout"
+	"nexablog/internal/services/moderation"
 	"nexablog/internal/services/permission"
 	"nexablog/internal/services/post"
+	"nexablog/internal/services/revision"
+	"nexablog/internal/services/tag"
 	"nexablog/internal/services/token"
+	"nexablog/internal/services/totp"
 	"nexablog/internal/services/user"
+	"nexablog/internal/telemetry"
 	"nexablog/internal/utils"
 	"nexablog/pkg/lib"
 )
@@ -21,28 +30,58 @@ func (app *App) loadRoutes() {
 	api := chi.NewRouter()
 
 	api.Use(
+		app.requestID,
+		app.traceRequest,
+		app.logRequest,
+		app.instrument,
 		app.recoverer,
-		middleware.Logger,
 		app.authenticate,
 	)
 
-	api.Get("/", app.wrap(handlers.Welcome))
+	api.With(app.rateLimit("default")).Get("/", app.wrap(handlers.Welcome))
 
-	api.Route("/users", app.loadUserRoutes)
-	api.Route("/tokens", app.loadTokenRoutes)
-	api.Route("/posts", app.loadPostRoutes)
+	api.With(
+		app.rateLimit("feeds"),
+	).Get("/feed.{format:rss|atom|json}", app.wrap(app.feedHandler().SiteFeed))
+
+	api.With(app.rateLimit("users")).Route("/users", app.loadUserRoutes)
+	api.With(app.rateLimit("tokens")).Route("/tokens", app.loadTokenRoutes)
+	api.With(app.rateLimit("oidc")).Route("/oidc", app.loadOIDCRoutes)
+	api.With(app.rateLimit("admin")).Route("/admin", app.loadAdminRoutes)
+	api.With(app.rateLimit("posts")).Route("/posts", app.loadPostRoutes)
+	api.With(app.rateLimit("tags")).Route("/tags", app.loadTagRoutes)
+
+	app.mux.Get("/healthz", app.healthz)
+	app.mux.Get("/readyz", app.readyz)
 
 	app.mux.Mount("/api", api)
 }
 
+// loadAdminServerRoutes serves operational endpoints on the admin port, which
+// is not meant to be exposed publicly.
+func (app *App) loadAdminServerRoutes() {
+	app.adminMux.Method(http.MethodGet, "/metrics", telemetry.MetricsHandler())
+}
+
 func (app *App) loadPostRoutes(r chi.Router) {
 	postSvc := post.NewService(app.repos.post)
 
 	h := handlers.Post{
-		PostSvc: postSvc,
+		PostSvc:       postSvc,
+		ModerationSvc: moderation.NewService(app.repos.moderation),
+		AuditSvc:      audit.NewService(app.repos.audit),
+		Policy:        app.policy,
 	}
 
 	r.Get("/", app.wrap(h.FindAllPosts))
+	r.Get("/search", app.wrap(h.SearchPosts))
+
+	r.With(
+		app.requireAuth,
+		app.requirePermission("posts:moderate"),
+	).Get("/moderation", app.wrap(h.FindModerations))
+
+	r.Get("/by-slug/{slug:[a-z0-9-]+}", app.wrap(h.FindPostBySlug))
 
 	r.With(
 		app.requireAuth,
@@ -55,6 +94,7 @@ func (app *App) loadPostRoutes(r chi.Router) {
 
 	r.With(
 		app.requireAuth,
+		app.requirePermission("posts:write"),
 		app.disallowInvalidPostID,
 	).Delete("/{post-id:^[0-9]+}", app.wrap(h.DeletePostByID))
 
@@ -63,36 +103,244 @@ func (app *App) loadPostRoutes(r chi.Router) {
 		app.requirePermission("posts:write"),
 		app.disallowInvalidPostID,
 	).Put("/{post-id:[0-9]+}", app.wrap(h.UpdatePostByID))
+
+	r.Route("/{post-id:[0-9]+}/revisions", app.loadRevisionRoutes)
+	r.Route("/{post-id:[0-9]+}/comments", app.loadCommentRoutes)
 }
 
-func (app *App) loadTokenRoutes(r chi.Router) {
-	userSvc := user.NewService(app.repos.user)
-	tokenSvc := token.NewService(app.repos.token)
+func (app *App) loadCommentRoutes(r chi.Router) {
+	postSvc := post.NewService(app.repos.post)
+	commentSvc := comment.NewService(app.repos.comment)
 
-	h := handlers.Token{
-		UserSvc:  userSvc,
-		TokenSvc: tokenSvc,
+	h := handlers.Comment{
+		PostSvc:    postSvc,
+		CommentSvc: commentSvc,
 	}
 
+	r.Use(app.disallowInvalidPostID)
+
+	r.Get("/", app.wrap(h.FindComments))
+	r.Get("/{comment-id:[0-9]+}/replies", app.wrap(h.FindReplies))
+
+	r.With(
+		app.requireAuth,
+		app.requirePermission("comments:write"),
+	).Post("/", app.wrap(h.CreateComment))
+
+	r.With(
+		app.requireAuth,
+		app.requirePermission("comments:write"),
+	).Put("/{comment-id:[0-9]+}", app.wrap(h.UpdateCommentByID))
+
+	r.With(
+		app.requireAuth,
+		app.requirePermission("comments:write"),
+	).Delete("/{comment-id:[0-9]+}", app.wrap(h.DeleteCommentByID))
+}
+
+func (app *App) loadRevisionRoutes(r chi.Router) {
+	postSvc := post.NewService(app.repos.post)
+	revisionSvc := revision.NewService(app.repos.revision)
+
+	h := handlers.Revision{
+		PostSvc:     postSvc,
+		RevisionSvc: revisionSvc,
+		AuditSvc:    audit.NewService(app.repos.audit),
+	}
+
+	r.Use(
+		app.requireAuth,
+		app.disallowInvalidPostID,
+	)
+
+	r.Group(func(r chi.Router) {
+		r.Use(app.requirePermission("posts:read"))
+
+		r.Get("/", app.wrap(h.FindRevisionsByPost))
+		r.Get("/diff", app.wrap(h.DiffRevisions))
+		r.Get("/{version:[0-9]+}", app.wrap(h.FindRevision))
+	})
+
+	r.With(
+		app.requirePermission("posts:write"),
+	).Post("/{version:[0-9]+}/restore", app.wrap(h.RestoreRevision))
+}
+
+func (app *App) loadTagRoutes(r chi.Router) {
+	tagSvc := tag.NewService(app.repos.tag)
+
+	h := handlers.Tag{
+		TagSvc: tagSvc,
+	}
+
+	r.Get("/", app.wrap(h.FindAllTags))
+
+	r.With(
+		app.requireAuth,
+		app.requirePermission("tags:manage"),
+	).Put("/{tag}", app.wrap(h.RenameTag))
+
+	r.With(
+		app.requireAuth,
+		app.requirePermission("tags:manage"),
+	).Post("/{tag}/merge", app.wrap(h.MergeTags))
+}
+
+func (app *App) loadTokenRoutes(r chi.Router) {
+	h := app.tokenHandler()
+
 	r.Post("/authenticate", app.wrap(h.GetToken))
+	r.Post("/refresh", app.wrap(h.RefreshToken))
+
+	r.With(
+		app.requireAuth,
+		app.disallowPersonalToken,
+	).Delete("/", app.wrap(h.RevokeAllTokens))
+
+	r.With(
+		app.requireAuth,
+	).Delete("/current", app.wrap(h.RevokeCurrentToken))
+	r.Post("/password-reset", app.wrap(h.RequestPasswordReset))
+	r.Post("/activation", app.wrap(h.RequestActivation))
 }
 
 func (app *App) loadUserRoutes(r chi.Router) {
 	userSvc := user.NewService(app.repos.user)
 	permissionSvc := permission.NewService(app.repos.permission)
 	postSvc := post.NewService(app.repos.post)
+	tokenSvc := token.NewService(app.repos.token)
 
 	h := handlers.User{
 		UserSvc:       userSvc,
 		PermissionSvc: permissionSvc,
 		PostSvc:       postSvc,
+		TokenSvc:      tokenSvc,
+		AuditSvc:      audit.NewService(app.repos.audit),
+		Mailer:        app.mailer,
 	}
 
 	r.Post("/", app.wrap(h.Register))
+	r.Put("/activate", app.wrap(h.Activate))
+	r.Put("/password", app.wrap(h.ResetPassword))
 
 	r.With(
 		app.requireAuth,
 	).Get("/me", app.wrap(h.GetMe))
+
+	r.With(
+		app.requireAuth,
+		app.disallowPersonalToken,
+	).Get("/me/sessions", app.wrap(h.FindSessions))
+
+	r.With(
+		app.requireAuth,
+		app.disallowPersonalToken,
+	).Delete("/me/sessions/{session-id}", app.wrap(h.DeleteSession))
+
+	r.With(
+		app.requireAuth,
+		app.disallowPersonalToken,
+	).Get("/me/tokens", app.wrap(h.FindPersonalTokens))
+
+	r.With(
+		app.requireAuth,
+		app.disallowPersonalToken,
+	).Post("/me/tokens", app.wrap(h.CreatePersonalToken))
+
+	r.With(
+		app.requireAuth,
+		app.disallowPersonalToken,
+	).Delete("/me/tokens/{token-id:[0-9]+}", app.wrap(h.DeletePersonalToken))
+
+	r.Route("/me/totp", app.loadTOTPRoutes)
+
+	r.Get("/{user-id:[0-9]+}/feed.{format:rss|atom|json}", app.wrap(app.feedHandler().AuthorFeed))
+}
+
+func (app *App) loadTOTPRoutes(r chi.Router) {
+	h := handlers.TOTP{
+		TOTPSvc: totp.NewService(app.repos.user, app.cfg.SiteTitle),
+	}
+
+	r.Use(app.requireAuth, app.disallowPersonalToken)
+
+	r.Post("/", app.wrap(h.Enroll))
+	r.Post("/confirm", app.wrap(h.Confirm))
+	r.Delete("/", app.wrap(h.Disable))
+}
+
+func (app *App) loadOIDCRoutes(r chi.Router) {
+	h := handlers.OIDC{
+		IdentitySvc:   identity.NewService(app.repos.identity, app.oidc),
+		UserSvc:       user.NewService(app.repos.user),
+		PermissionSvc: permission.NewService(app.repos.permission),
+		Token:         app.tokenHandler(),
+	}
+
+	r.Get("/{provider}/login", app.wrap(h.Login))
+	r.Get("/{provider}/callback", app.wrap(h.Callback))
+}
+
+func (app *App) loadAdminRoutes(r chi.Router) {
+	auditSvc := audit.NewService(app.repos.audit)
+
+	h := handlers.Admin{
+		UserSvc:       user.NewService(app.repos.user),
+		PermissionSvc: permission.NewService(app.repos.permission),
+		AuditSvc:      auditSvc,
+	}
+
+	auditHandler := handlers.Audit{
+		AuditSvc: auditSvc,
+	}
+
+	r.Use(app.requireAuth, app.requirePermission("users:manage"))
+
+	r.Get("/audit", app.wrap(auditHandler.FindEntries))
+	r.Get("/roles", app.wrap(h.FindRoles))
+	r.Get("/permissions", app.wrap(h.FindPermissions))
+
+	r.Route("/users/{user-id:[0-9]+}", func(r chi.Router) {
+		r.Get("/access", app.wrap(h.FindUserAccess))
+		r.Put("/roles/{role}", app.wrap(h.GrantRole))
+		r.Delete("/roles/{role}", app.wrap(h.RevokeRole))
+		r.Put("/permissions/{code}", app.wrap(h.GrantPermission))
+		r.Delete("/permissions/{code}", app.wrap(h.RevokePermission))
+	})
+}
+
+func (app *App) tokenHandler() *handlers.Token {
+	return &handlers.Token{
+		UserSvc:    user.NewService(app.repos.user),
+		TokenSvc:   token.NewService(app.repos.token),
+		TOTPSvc:    totp.NewService(app.repos.user, app.cfg.SiteTitle),
+		AuditSvc:   audit.NewService(app.repos.audit),
+		LockoutSvc: lockout.NewService(app.repos.lockout, app.lockoutPolicy()),
+		Mailer:     app.mailer,
+		AccessTTL:  app.cfg.Token.AccessTTL,
+		RefreshTTL: app.cfg.Token.RefreshTTL,
+	}
+}
+
+func (app *App) lockoutPolicy() lockout.Policy {
+	return lockout.Policy{
+		AccountBackoffAfter: app.cfg.Lockout.AccountBackoffAfter,
+		IPBackoffAfter:      app.cfg.Lockout.IPBackoffAfter,
+		LockAfter:           app.cfg.Lockout.LockAfter,
+		BackoffBase:         app.cfg.Lockout.BackoffBase,
+		BackoffMax:          app.cfg.Lockout.BackoffMax,
+		LockDuration:        app.cfg.Lockout.LockDuration,
+		Window:              app.cfg.Lockout.Window,
+	}
+}
+
+func (app *App) feedHandler() *handlers.Feed {
+	return &handlers.Feed{
+		PostSvc:   post.NewService(app.repos.post),
+		UserSvc:   user.NewService(app.repos.user),
+		SiteTitle: app.cfg.SiteTitle,
+		SiteURL:   app.cfg.SiteURL,
+	}
 }
 
 func (app *App) wrap(f utils.ApiFunc) http.HandlerFunc {
@@ -108,7 +356,8 @@ func (app *App) wrap(f utils.ApiFunc) http.HandlerFunc {
 			})
 			return
 		case err != nil:
-			log.Println(err)
+			slog.ErrorContext(r.Context(), "request failed", "error", err)
+			trace.SpanFromContext(r.Context()).RecordError(err)
 			_ = utils.WriteJson(w, http.StatusInternalServerError, lib.H[string]{
 				"detail": "internal server error",
 			})
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"nexablog/internal/services/lockout"
+	"nexablog/internal/services/post"
+)
+
+func (app *App) runScheduler(ctx context.Context) {
+	postSvc := post.NewService(app.repos.post)
+	lockoutSvc := lockout.NewService(app.repos.lockout, app.lockoutPolicy())
+
+	ticker := time.NewTicker(time.Duration(app.cfg.SchedulerInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := lockoutSvc.PurgeStale(ctx); err != nil {
+				slog.ErrorContext(ctx, "could not purge login throttles", "error", err)
+			}
+
+			n, err := postSvc.PublishScheduledPosts(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "could not publish scheduled posts", "error", err)
+				continue
+			}
+
+			if n > 0 {
+				slog.InfoContext(ctx, "published scheduled posts", "count", n)
+			}
+		}
+	}
+}
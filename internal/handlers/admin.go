@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"nexablog/internal/models"
+	"nexablog/internal/services"
+	"nexablog/internal/services/audit"
+	"nexablog/internal/services/permission"
+	"nexablog/internal/services/user"
+	"nexablog/internal/utils"
+)
+
+type Admin struct {
+	UserSvc       user.Service
+	PermissionSvc permission.Service
+	AuditSvc      audit.Service
+}
+
+func (h *Admin) FindRoles(w http.ResponseWriter, r *http.Request) error {
+	roles, err := h.PermissionSvc.FindAllRoles(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, roles)
+}
+
+func (h *Admin) FindPermissions(w http.ResponseWriter, r *http.Request) error {
+	permissions, err := h.PermissionSvc.FindAllPermissions(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, permissions.Codes())
+}
+
+func (h *Admin) FindUserAccess(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.targetUser(r)
+	if err != nil {
+		return err
+	}
+
+	access, err := h.PermissionSvc.FindUserAccess(r.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, access)
+}
+
+func (h *Admin) GrantRole(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.targetUser(r)
+	if err != nil {
+		return err
+	}
+
+	role := chi.URLParam(r, "role")
+
+	roles, err := h.PermissionSvc.FindAllRoles(r.Context())
+	if err != nil {
+		return err
+	}
+
+	if !roles.Include(role) {
+		return utils.NewApiError("role not found", http.StatusNotFound)
+	}
+
+	before, err := h.PermissionSvc.FindUserAccess(r.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.PermissionSvc.AddUserRole(r.Context(), user.UserID, role); err != nil {
+		return err
+	}
+
+	return h.writeAccess(w, r, models.AuditRoleGrant, before)
+}
+
+func (h *Admin) RevokeRole(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.targetUser(r)
+	if err != nil {
+		return err
+	}
+
+	role := chi.URLParam(r, "role")
+
+	if role == models.RoleAdmin && utils.GetUser(r).IsOwner(user.UserID) {
+		return utils.NewApiError("you cannot revoke your own admin role", http.StatusConflict)
+	}
+
+	before, err := h.PermissionSvc.FindUserAccess(r.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = h.PermissionSvc.RemoveUserRole(r.Context(), user.UserID, role)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("user does not have this role", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return h.writeAccess(w, r, models.AuditRoleRevoke, before)
+}
+
+func (h *Admin) GrantPermission(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.targetUser(r)
+	if err != nil {
+		return err
+	}
+
+	code := chi.URLParam(r, "code")
+
+	permissions, err := h.PermissionSvc.FindAllPermissions(r.Context())
+	if err != nil {
+		return err
+	}
+
+	if !permissions.Include(code) {
+		return utils.NewApiError("permission not found", http.StatusNotFound)
+	}
+
+	before, err := h.PermissionSvc.FindUserAccess(r.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.PermissionSvc.AddUserPermission(r.Context(), user.UserID, code); err != nil {
+		return err
+	}
+
+	return h.writeAccess(w, r, models.AuditPermissionGrant, before)
+}
+
+func (h *Admin) RevokePermission(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.targetUser(r)
+	if err != nil {
+		return err
+	}
+
+	before, err := h.PermissionSvc.FindUserAccess(r.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = h.PermissionSvc.RemoveUserPermission(r.Context(), user.UserID, chi.URLParam(r, "code"))
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("user was not granted this permission directly", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return h.writeAccess(w, r, models.AuditPermissionRevoke, before)
+}
+
+func (h *Admin) targetUser(r *http.Request) (models.User, error) {
+	userID, _ := strconv.Atoi(chi.URLParam(r, "user-id"))
+
+	user, err := h.UserSvc.FindUserByID(r.Context(), userID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return models.User{}, utils.NewApiError("user not found", http.StatusNotFound)
+	}
+
+	return user, err
+}
+
+// writeAccess records the change from before to the user's current access and
+// writes the latter.
+func (h *Admin) writeAccess(
+	w http.ResponseWriter,
+	r *http.Request,
+	action models.AuditAction,
+	before models.UserAccess,
+) error {
+	access, err := h.PermissionSvc.FindUserAccess(r.Context(), before.UserID)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(before.UserID),
+		Before:     before,
+		After:      access,
+	})
+
+	return utils.WriteJson(w, http.StatusOK, access)
+}
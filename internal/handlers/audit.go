@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"nexablog/internal/models"
+	"nexablog/internal/services/audit"
+	"nexablog/internal/utils"
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
+
+type Audit struct {
+	AuditSvc audit.Service
+}
+
+func (h *Audit) FindEntries(w http.ResponseWriter, r *http.Request) error {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filter := models.AuditFilter{
+		Limit:      utils.ReadInt(qs, "limit", 50, v),
+		Cursor:     utils.ReadString(qs, "cursor", ""),
+		Action:     utils.ReadString(qs, "action", ""),
+		ActorID:    utils.ReadInt(qs, "actor_id", 0, v),
+		TargetType: utils.ReadString(qs, "target_type", ""),
+		TargetID:   utils.ReadString(qs, "target_id", ""),
+		Since:      utils.ReadTime(qs, "since", v),
+		Until:      utils.ReadTime(qs, "until", v),
+	}
+
+	if models.ValidateAuditFilter(v, &filter); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	page, err := h.AuditSvc.FindEntries(r.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, page)
+}
+
+// recordAudit fills in the request's IP, user agent and, unless already set,
+// the authenticated user as actor. Failures are logged rather than returned
+// so that auditing never undoes an action that has already happened.
+func recordAudit(svc audit.Service, r *http.Request, entry models.AuditIn) {
+	if user := utils.GetUser(r); entry.ActorID == 0 && !user.IsAnonymousUser() {
+		entry.ActorID = user.UserID
+	}
+
+	entry.IP = utils.ClientIP(r)
+	entry.UserAgent = utils.UserAgent(r)
+
+	if err := svc.Record(r.Context(), entry); err != nil {
+		slog.ErrorContext(r.Context(), "could not record audit entry", "error", err, "action", entry.Action)
+	}
+}
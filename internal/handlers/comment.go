@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"nexablog/internal/models"
+	"nexablog/internal/services"
+	"nexablog/internal/services/comment"
+	"nexablog/internal/services/post"
+	"nexablog/internal/utils"
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
+
+type Comment struct {
+	PostSvc    post.Service
+	CommentSvc comment.Service
+}
+
+func (h *Comment) CreateComment(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findVisiblePost(r)
+	if err != nil {
+		return err
+	}
+
+	payload := models.CommentIn{}
+
+	if err := utils.ReadJson(w, r, &payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if models.ValidateComment(v, &payload); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	payload.PostID = post.PostID
+	payload.AuthorID = utils.GetUser(r).UserID
+
+	comment, err := h.CommentSvc.CreateComment(r.Context(), payload)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("parent comment not found", http.StatusUnprocessableEntity)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusCreated, comment)
+}
+
+func (h *Comment) FindComments(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findVisiblePost(r)
+	if err != nil {
+		return err
+	}
+
+	return h.writeCommentPage(w, r, post.PostID, 0)
+}
+
+func (h *Comment) FindReplies(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findVisiblePost(r)
+	if err != nil {
+		return err
+	}
+
+	commentID, _ := strconv.Atoi(chi.URLParam(r, "comment-id"))
+
+	_, err = h.CommentSvc.FindCommentByID(r.Context(), post.PostID, commentID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("comment not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return h.writeCommentPage(w, r, post.PostID, commentID)
+}
+
+func (h *Comment) UpdateCommentByID(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findVisiblePost(r)
+	if err != nil {
+		return err
+	}
+
+	commentID, _ := strconv.Atoi(chi.URLParam(r, "comment-id"))
+
+	comment, err := h.CommentSvc.FindCommentByID(r.Context(), post.PostID, commentID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("comment not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if comment.Deleted {
+		return utils.NewApiError("comment not found", http.StatusNotFound)
+	}
+
+	if !utils.GetUser(r).IsOwner(comment.AuthorID) {
+		return utils.NewApiError("not allowed", http.StatusForbidden)
+	}
+
+	payload := models.CommentIn{}
+
+	if err := utils.ReadJson(w, r, &payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if models.ValidateComment(v, &payload); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	comment, err = h.CommentSvc.UpdateCommentByID(
+		r.Context(),
+		payload.Body,
+		comment.CommentID,
+		comment.Version,
+	)
+
+	if errors.Is(err, services.ErrUpdateConflict) {
+		return utils.NewApiError("comment was modified, try again", http.StatusConflict)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, comment)
+}
+
+func (h *Comment) DeleteCommentByID(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findVisiblePost(r)
+	if err != nil {
+		return err
+	}
+
+	commentID, _ := strconv.Atoi(chi.URLParam(r, "comment-id"))
+
+	comment, err := h.CommentSvc.FindCommentByID(r.Context(), post.PostID, commentID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("comment not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	user := utils.GetUser(r)
+
+	if !user.IsOwner(comment.AuthorID) && !user.IsOwner(post.AuthorID) {
+		return utils.NewApiError("not allowed", http.StatusForbidden)
+	}
+
+	err = h.CommentSvc.DeleteCommentByID(r.Context(), comment.CommentID)
+
+	if err != nil && !errors.Is(err, services.ErrResourceNotFound) {
+		return err
+	}
+
+	return utils.SendStatus(w, http.StatusNoContent)
+}
+
+func (h *Comment) writeCommentPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	postID, parentID int,
+) error {
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	filter := models.CommentFilter{
+		PostID:   postID,
+		ParentID: parentID,
+		Limit:    utils.ReadInt(qs, "limit", 20, v),
+		Cursor:   utils.ReadString(qs, "cursor", ""),
+	}
+
+	if models.ValidateCommentFilter(v, &filter); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	page, err := h.CommentSvc.FindComments(r.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, page)
+}
+
+func (h *Comment) findVisiblePost(r *http.Request) (models.Post, error) {
+	postID, _ := strconv.Atoi(chi.URLParam(r, "post-id"))
+
+	post, err := h.PostSvc.FindPostByID(r.Context(), postID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return models.Post{}, utils.NewApiError("post not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	if !post.VisibleTo(utils.GetUser(r)) {
+		return models.Post{}, utils.NewApiError("post not found", http.StatusNotFound)
+	}
+
+	return post, nil
+}
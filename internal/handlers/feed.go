@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nexablog/internal/models"
+	"nexablog/internal/services"
+	"nexablog/internal/services/post"
+	"nexablog/internal/services/user"
+	"nexablog/internal/utils"
+	"nexablog/pkg/feed"
+)
+
+type Feed struct {
+	PostSvc   post.Service
+	UserSvc   user.Service
+	SiteTitle string
+	SiteURL   string
+}
+
+func (h *Feed) SiteFeed(w http.ResponseWriter, r *http.Request) error {
+	f := &feed.Feed{
+		Title:       h.SiteTitle,
+		Link:        h.SiteURL,
+		FeedLink:    h.SiteURL + r.URL.Path,
+		Description: "Latest posts on " + h.SiteTitle,
+		Author:      h.SiteTitle,
+	}
+
+	return h.writeFeed(w, r, f, 0)
+}
+
+func (h *Feed) AuthorFeed(w http.ResponseWriter, r *http.Request) error {
+	userID, _ := strconv.Atoi(chi.URLParam(r, "user-id"))
+
+	user, err := h.UserSvc.FindUserByID(r.Context(), userID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("user not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	f := &feed.Feed{
+		Title:       user.Username + " on " + h.SiteTitle,
+		Link:        h.SiteURL,
+		FeedLink:    h.SiteURL + r.URL.Path,
+		Description: "Latest posts by " + user.Username,
+		Author:      user.Username,
+	}
+
+	return h.writeFeed(w, r, f, user.UserID)
+}
+
+func (h *Feed) writeFeed(w http.ResponseWriter, r *http.Request, f *feed.Feed, authorID int) error {
+	format := chi.URLParam(r, "format")
+
+	state, err := h.PostSvc.FindFeedState(r.Context(), authorID)
+	if err != nil {
+		return err
+	}
+
+	lastModified := state.LastModified.UTC().Truncate(time.Second)
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf(
+		"%s|%s|%d|%d|%d",
+		format,
+		f.FeedLink,
+		state.LastModified.UnixNano(),
+		state.Count,
+		state.LatestID,
+	)))
+	etag := fmt.Sprintf(`W/"%x"`, sum[:12])
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if notModified(r, etag, lastModified) {
+		return utils.SendStatus(w, http.StatusNotModified)
+	}
+
+	page, err := h.PostSvc.FindAllPosts(r.Context(), models.PostFilter{
+		Limit:    50,
+		Sort:     models.PostSortCreatedAt,
+		Order:    models.OrderDesc,
+		AuthorID: authorID,
+	})
+	if err != nil {
+		return err
+	}
+
+	f.Updated = lastModified
+	f.Items = make([]feed.Item, 0, len(page.Posts))
+
+	for _, p := range page.Posts {
+		published := p.CreatedAt
+		if p.PublishedAt != nil {
+			published = *p.PublishedAt
+		}
+
+		item := feed.Item{
+			ID:        h.SiteURL + "/api/posts/" + strconv.Itoa(p.PostID),
+			Title:     p.Title,
+			Link:      h.SiteURL + "/api/posts/by-slug/" + p.Slug,
+			Content:   p.Body,
+			Tags:      p.Tags,
+			Published: published,
+			Updated:   published,
+		}
+
+		if authorID > 0 {
+			item.Author = f.Author
+		}
+
+		f.Items = append(f.Items, item)
+	}
+
+	switch format {
+	case "rss":
+		w.Header().Set("Content-Type", feed.ContentTypeRSS)
+		return feed.WriteRSS(w, f)
+	case "atom":
+		w.Header().Set("Content-Type", feed.ContentTypeAtom)
+		return feed.WriteAtom(w, f)
+	default:
+		w.Header().Set("Content-Type", feed.ContentTypeJSON)
+		return feed.WriteJSON(w, f)
+	}
+}
+
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return inm == etag || inm == "*"
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.After(ims)
+}
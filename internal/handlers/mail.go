@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nexablog/internal/mailer"
+	"nexablog/internal/models"
+)
+
+func sendMail(m mailer.Mailer, msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := m.Send(ctx, msg); err != nil {
+			slog.Error("could not send mail", "error", err, "subject", msg.Subject)
+		}
+	}()
+}
+
+func lockoutMessage(user models.User, ip string, until time.Time) mailer.Message {
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Your nexablog account has been locked",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"After too many failed sign-in attempts, the last from %s, your account has been locked.\n\n"+
+				"You can sign in again after %s. If these attempts were not yours, consider resetting your password.\n",
+			user.Username,
+			ip,
+			until.UTC().Format(time.RFC1123),
+		),
+	}
+}
+
+func activationMessage(user models.User, token models.TokenOut) mailer.Message {
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Activate your nexablog account",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Use the token below with PUT /api/users/activate to activate your account.\n\n"+
+				"%s\n\n"+
+				"The token expires at %s.\n",
+			user.Username,
+			token.Plain,
+			token.ExpiresAt.UTC().Format(time.RFC1123),
+		),
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"nexablog/internal/models"
+	"nexablog/internal/services"
+	"nexablog/internal/services/identity"
+	"nexablog/internal/services/permission"
+	"nexablog/internal/services/user"
+	"nexablog/internal/utils"
+	"nexablog/pkg/oidc"
+)
+
+type OIDC struct {
+	IdentitySvc   identity.Service
+	UserSvc       user.Service
+	PermissionSvc permission.Service
+	Token         *Token
+}
+
+func (h *OIDC) Login(w http.ResponseWriter, r *http.Request) error {
+	location, err := h.IdentitySvc.StartLogin(r.Context(), chi.URLParam(r, "provider"))
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("identity provider not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, location, http.StatusFound)
+	return nil
+}
+
+func (h *OIDC) Callback(w http.ResponseWriter, r *http.Request) error {
+	provider := chi.URLParam(r, "provider")
+	qs := r.URL.Query()
+
+	if qs.Get("error") != "" {
+		return utils.NewApiError("identity provider returned "+qs.Get("error"), http.StatusUnauthorized)
+	}
+
+	claims, err := h.IdentitySvc.CompleteLogin(
+		r.Context(),
+		provider,
+		qs.Get("code"),
+		qs.Get("state"),
+	)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("identity provider not found", http.StatusNotFound)
+	}
+
+	if errors.Is(err, services.ErrInvalidCode) {
+		return utils.NewApiError("invalid or expired login attempt", http.StatusUnauthorized)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !claims.EmailVerified || claims.Email == "" {
+		return utils.NewApiError("the identity provider did not verify an email", http.StatusForbidden)
+	}
+
+	user, err := h.resolveUser(r, provider, claims)
+	if err != nil {
+		return err
+	}
+
+	return h.Token.login(w, r, user, "oidc:"+provider)
+}
+
+// resolveUser finds the user linked to the identity, linking an existing
+// account by email or creating a new one when there is none. An existing
+// account that was never activated is claimed rather than trusted, since
+// whoever registered it did not prove they own the email.
+func (h *OIDC) resolveUser(r *http.Request, provider string, claims oidc.Claims) (models.User, error) {
+	userID, err := h.IdentitySvc.FindUserIDByIdentity(r.Context(), provider, claims.Subject)
+
+	if err == nil {
+		return h.UserSvc.FindUserByID(r.Context(), userID)
+	}
+
+	if !errors.Is(err, services.ErrResourceNotFound) {
+		return models.User{}, err
+	}
+
+	user, err := h.UserSvc.FindUserByEmail(r.Context(), claims.Email)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		user, err = h.createUser(r, claims)
+	}
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if !user.Activated {
+		if user, err = h.claimUser(r, user.UserID); err != nil {
+			return models.User{}, err
+		}
+
+		err = h.PermissionSvc.AddUserRole(
+			r.Context(),
+			user.UserID,
+			models.RoleReader,
+			models.RoleAuthor,
+		)
+		if err != nil {
+			return models.User{}, err
+		}
+	}
+
+	err = h.IdentitySvc.LinkIdentity(r.Context(), models.Identity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		UserID:   user.UserID,
+		Email:    claims.Email,
+	})
+	if err != nil && !errors.Is(err, services.ErrDuplicateKey) {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (h *OIDC) claimUser(r *http.Request, userID int) (models.User, error) {
+	password, err := utils.GenRandString(32)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user, err := h.UserSvc.ClaimUser(r.Context(), userID, password)
+
+	if errors.Is(err, services.ErrUpdateConflict) {
+		return models.User{}, utils.NewApiError("account was modified, try again", http.StatusConflict)
+	}
+
+	return user, err
+}
+
+func (h *OIDC) createUser(r *http.Request, claims oidc.Claims) (models.User, error) {
+	username := claims.PreferredUsername
+
+	if username == "" {
+		username = claims.Name
+	}
+
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	password, err := utils.GenRandString(32)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return h.UserSvc.CreateUser(r.Context(), models.UserIn{
+		Username: username,
+		Email:    claims.Email,
+		Password: password,
+	})
+}
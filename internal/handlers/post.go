@@ -4,11 +4,15 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 
 	"nexablog/internal/models"
+	"nexablog/internal/policy"
 	"nexablog/internal/services"
+	"nexablog/internal/services/audit"
+	"nexablog/internal/services/moderation"
 	"nexablog/internal/services/post"
 	"nexablog/internal/utils"
 	"nexablog/pkg/lib"
@@ -16,7 +20,10 @@ import (
 )
 
 type Post struct {
-	PostSvc post.Service
+	PostSvc       post.Service
+	ModerationSvc moderation.Service
+	AuditSvc      audit.Service
+	Policy        *policy.Policy
 }
 
 func (h *Post) CreatePost(w http.ResponseWriter, r *http.Request) error {
@@ -26,6 +33,12 @@ func (h *Post) CreatePost(w http.ResponseWriter, r *http.Request) error {
 		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
 	}
 
+	if payload.Status == "" {
+		payload.Status = models.PostStatusDraft
+	}
+
+	payload.SetTags([]string{})
+
 	v := validator.New()
 
 	if models.ValidatePost(v, &payload); !v.Valid() {
@@ -35,22 +48,74 @@ func (h *Post) CreatePost(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	payload.AuthorID = utils.GetUser(r).UserID
+	payload.EditorID = payload.AuthorID
 
 	post, err := h.PostSvc.CreatePost(r.Context(), payload)
 	if err != nil {
 		return err
 	}
 
+	auditPost(h.AuditSvc, r, models.AuditPostCreate, post.PostID, nil, post)
+
 	return utils.WriteJson(w, http.StatusCreated, post)
 }
 
 func (h *Post) FindAllPosts(w http.ResponseWriter, r *http.Request) error {
-	posts, err := h.PostSvc.FindAllPosts(r.Context())
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	filter := models.PostFilter{
+		Limit:         utils.ReadInt(qs, "limit", 20, v),
+		Sort:          utils.ReadString(qs, "sort", models.PostSortCreatedAt),
+		Order:         utils.ReadString(qs, "order", models.OrderDesc),
+		Cursor:        utils.ReadString(qs, "cursor", ""),
+		AuthorID:      utils.ReadInt(qs, "author_id", 0, v),
+		Status:        models.PostStatus(utils.ReadString(qs, "status", "")),
+		Tag:           models.NormalizeTag(utils.ReadString(qs, "tag", "")),
+		ViewerID:      utils.GetUser(r).UserID,
+		CreatedAfter:  utils.ReadTime(qs, "created_after", v),
+		CreatedBefore: utils.ReadTime(qs, "created_before", v),
+	}
+
+	if models.ValidatePostFilter(v, &filter); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	page, err := h.PostSvc.FindAllPosts(r.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, page)
+}
+
+func (h *Post) SearchPosts(w http.ResponseWriter, r *http.Request) error {
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	search := models.PostSearch{
+		Query:    utils.ReadString(qs, "q", ""),
+		Limit:    utils.ReadInt(qs, "limit", 20, v),
+		Cursor:   utils.ReadString(qs, "cursor", ""),
+		ViewerID: utils.GetUser(r).UserID,
+	}
+
+	if models.ValidatePostSearch(v, &search); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	page, err := h.PostSvc.SearchPosts(r.Context(), search)
 	if err != nil {
 		return err
 	}
 
-	return utils.WriteJson(w, http.StatusOK, posts)
+	return utils.WriteJson(w, http.StatusOK, page)
 }
 
 func (h *Post) FindPostByID(w http.ResponseWriter, r *http.Request) error {
@@ -68,9 +133,58 @@ func (h *Post) FindPostByID(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	if !post.VisibleTo(utils.GetUser(r)) {
+		return utils.WriteJson(w, http.StatusNotFound, lib.H[string]{
+			"detail": "post not found",
+		})
+	}
+
+	return utils.WriteJson(w, http.StatusOK, post)
+}
+
+func (h *Post) FindPostBySlug(w http.ResponseWriter, r *http.Request) error {
+	slug := chi.URLParam(r, "slug")
+
+	post, err := h.PostSvc.FindPostBySlug(r.Context(), slug)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return h.redirectSlug(w, r, slug)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !post.VisibleTo(utils.GetUser(r)) {
+		return utils.NewApiError("post not found", http.StatusNotFound)
+	}
+
 	return utils.WriteJson(w, http.StatusOK, post)
 }
 
+func (h *Post) redirectSlug(w http.ResponseWriter, r *http.Request, slug string) error {
+	post, err := h.PostSvc.FindPostByOldSlug(r.Context(), slug)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("post not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !post.VisibleTo(utils.GetUser(r)) {
+		return utils.NewApiError("post not found", http.StatusNotFound)
+	}
+
+	w.Header().Set("Location", "/api/posts/by-slug/"+post.Slug)
+
+	return utils.WriteJson(w, http.StatusMovedPermanently, lib.H[string]{
+		"detail": "post moved",
+		"slug":   post.Slug,
+	})
+}
+
 func (h *Post) DeletePostByID(w http.ResponseWriter, r *http.Request) error {
 	postID, _ := strconv.Atoi(chi.URLParam(r, "post-id"))
 
@@ -84,18 +198,23 @@ func (h *Post) DeletePostByID(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	user := utils.GetUser(r)
-
-	if !user.IsOwner(post.AuthorID) {
-		return utils.NewApiError("not allowed", http.StatusForbidden)
+	moderation, err := h.authorize(r, post, models.ModerationDelete)
+	if err != nil {
+		return err
 	}
 
-	err = h.PostSvc.DeletePostByID(r.Context(), postID)
+	err = h.PostSvc.DeletePostByID(r.Context(), postID, moderation)
 
-	if err != nil && !errors.Is(err, services.ErrResourceNotFound) {
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("post not found", http.StatusNotFound)
+	}
+
+	if err != nil {
 		return err
 	}
 
+	auditPost(h.AuditSvc, r, models.AuditPostDelete, post.PostID, post, nil)
+
 	return utils.SendStatus(w, http.StatusNoContent)
 }
 
@@ -112,10 +231,9 @@ func (h *Post) UpdatePostByID(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	user := utils.GetUser(r)
-
-	if !user.IsOwner(post.AuthorID) {
-		return utils.NewApiError("not allowed", http.StatusForbidden)
+	moderation, err := h.authorize(r, post, models.ModerationUpdate)
+	if err != nil {
+		return err
 	}
 
 	payload := models.PostIn{}
@@ -124,6 +242,16 @@ func (h *Post) UpdatePostByID(w http.ResponseWriter, r *http.Request) error {
 		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
 	}
 
+	if payload.Status == "" {
+		payload.Status = post.Status
+	}
+
+	payload.SetTags(post.Tags)
+
+	payload.EditorID = utils.GetUser(r).UserID
+
+	payload.SetPublishedAt(post)
+
 	v := validator.New()
 
 	if models.ValidatePost(v, &payload); !v.Valid() {
@@ -132,16 +260,107 @@ func (h *Post) UpdatePostByID(w http.ResponseWriter, r *http.Request) error {
 		})
 	}
 
-	post, err = h.PostSvc.UpdatePostByID(
+	updated, err := h.PostSvc.UpdatePostByID(
 		r.Context(),
 		payload,
 		post.PostID,
 		post.Version,
+		moderation,
 	)
 
-	if err != nil && !errors.Is(err, services.ErrResourceNotFound) {
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("post not found", http.StatusNotFound)
+	}
+
+	if errors.Is(err, services.ErrUpdateConflict) {
+		return utils.NewApiError("post was modified, try again", http.StatusConflict)
+	}
+
+	if err != nil {
 		return err
 	}
 
-	return utils.WriteJson(w, http.StatusOK, post)
+	auditPost(h.AuditSvc, r, models.AuditPostUpdate, post.PostID, post, updated)
+
+	return utils.WriteJson(w, http.StatusOK, updated)
+}
+
+func (h *Post) FindModerations(w http.ResponseWriter, r *http.Request) error {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filter := models.ModerationFilter{
+		PostID: utils.ReadInt(qs, "post_id", 0, v),
+		Limit:  utils.ReadInt(qs, "limit", 50, v),
+	}
+
+	if models.ValidateModerationFilter(v, &filter); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	moderations, err := h.ModerationSvc.FindModerations(r.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, moderations)
+}
+
+// authorize lets the author change their post and anyone holding
+// posts:moderate change everyone else's. It returns the moderation record to
+// write with the change, or nil when the author makes it. Moderators may give
+// a reason in the reason query parameter.
+func (h *Post) authorize(
+	r *http.Request,
+	post models.Post,
+	action models.ModerationAction,
+) (*models.ModerationIn, error) {
+	moderated, err := h.Policy.OwnerOr(r, post.AuthorID, "posts:moderate")
+
+	if errors.Is(err, policy.ErrForbidden) {
+		return nil, utils.NewApiError("not allowed", http.StatusForbidden)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	reason := r.URL.Query().Get("reason")
+
+	if utf8.RuneCountInString(reason) > 500 {
+		return nil, utils.NewApiError(
+			"reason must not be more than 500 characters",
+			http.StatusUnprocessableEntity,
+		)
+	}
+
+	if !moderated {
+		return nil, nil
+	}
+
+	return &models.ModerationIn{
+		PostID:      post.PostID,
+		AuthorID:    post.AuthorID,
+		ModeratorID: utils.GetUser(r).UserID,
+		Action:      action,
+		Reason:      reason,
+	}, nil
+}
+
+func auditPost(
+	svc audit.Service,
+	r *http.Request,
+	action models.AuditAction,
+	postID int,
+	before, after any,
+) {
+	recordAudit(svc, r, models.AuditIn{
+		Action:     action,
+		TargetType: "post",
+		TargetID:   strconv.Itoa(postID),
+		Before:     before,
+		After:      after,
+	})
 }
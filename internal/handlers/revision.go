@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"nexablog/internal/models"
+	"nexablog/internal/services"
+	"nexablog/internal/services/audit"
+	"nexablog/internal/services/post"
+	"nexablog/internal/services/revision"
+	"nexablog/internal/utils"
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
+
+type Revision struct {
+	PostSvc     post.Service
+	RevisionSvc revision.Service
+	AuditSvc    audit.Service
+}
+
+func (h *Revision) FindRevisionsByPost(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findOwnedPost(r)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := h.RevisionSvc.FindRevisionsByPost(r.Context(), post.PostID)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, revisions)
+}
+
+func (h *Revision) FindRevision(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findOwnedPost(r)
+	if err != nil {
+		return err
+	}
+
+	version, _ := strconv.Atoi(chi.URLParam(r, "version"))
+
+	revision, err := h.RevisionSvc.FindRevision(r.Context(), post.PostID, version)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("revision not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, revision)
+}
+
+func (h *Revision) DiffRevisions(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findOwnedPost(r)
+	if err != nil {
+		return err
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	from := utils.ReadInt(qs, "from", max(1, post.Version-1), v)
+	to := utils.ReadInt(qs, "to", post.Version, v)
+
+	v.Check(from >= 1, "from", "must be greater than zero")
+	v.Check(to >= 1, "to", "must be greater than zero")
+
+	if !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	out, err := h.RevisionSvc.DiffRevisions(r.Context(), post.PostID, from, to)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("revision not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, out)
+}
+
+func (h *Revision) RestoreRevision(w http.ResponseWriter, r *http.Request) error {
+	post, err := h.findOwnedPost(r)
+	if err != nil {
+		return err
+	}
+
+	version, _ := strconv.Atoi(chi.URLParam(r, "version"))
+
+	revision, err := h.RevisionSvc.FindRevision(r.Context(), post.PostID, version)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("revision not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	payload := models.PostIn{
+		Title:       revision.Title,
+		Body:        revision.Body,
+		Status:      post.Status,
+		PublishedAt: post.PublishedAt,
+		Tags:        &post.Tags,
+		EditorID:    utils.GetUser(r).UserID,
+	}
+
+	updated, err := h.PostSvc.UpdatePostByID(
+		r.Context(),
+		payload,
+		post.PostID,
+		post.Version,
+		nil,
+	)
+
+	if errors.Is(err, services.ErrUpdateConflict) {
+		return utils.NewApiError("post was modified, try again", http.StatusConflict)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	auditPost(h.AuditSvc, r, models.AuditPostUpdate, post.PostID, post, updated)
+
+	return utils.WriteJson(w, http.StatusOK, updated)
+}
+
+func (h *Revision) findOwnedPost(r *http.Request) (models.Post, error) {
+	postID, _ := strconv.Atoi(chi.URLParam(r, "post-id"))
+
+	post, err := h.PostSvc.FindPostByID(r.Context(), postID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return models.Post{}, utils.NewApiError("post not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	if !utils.GetUser(r).IsOwner(post.AuthorID) {
+		return models.Post{}, utils.NewApiError("not allowed", http.StatusForbidden)
+	}
+
+	return post, nil
+}
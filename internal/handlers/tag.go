@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+
+	"nexablog/internal/models"
+	"nexablog/internal/services"
+	"nexablog/internal/services/tag"
+	"nexablog/internal/utils"
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
+
+type Tag struct {
+	TagSvc tag.Service
+}
+
+func (h *Tag) FindAllTags(w http.ResponseWriter, r *http.Request) error {
+	tags, err := h.TagSvc.FindAllTags(r.Context())
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, tags)
+}
+
+func (h *Tag) RenameTag(w http.ResponseWriter, r *http.Request) error {
+	payload := new(struct {
+		Name string `json:"name"`
+	})
+
+	if err := utils.ReadJson(w, r, payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	payload.Name = models.NormalizeTag(payload.Name)
+
+	v := validator.New()
+
+	if models.ValidateTagName(v, "name", payload.Name); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	name := tagParam(r)
+
+	tag, err := h.TagSvc.RenameTag(r.Context(), name, payload.Name)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("tag not found", http.StatusNotFound)
+	}
+
+	if errors.Is(err, services.ErrDuplicateKey) {
+		return utils.NewApiError("tag already exists, merge instead", http.StatusConflict)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, tag)
+}
+
+func (h *Tag) MergeTags(w http.ResponseWriter, r *http.Request) error {
+	payload := new(struct {
+		Into string `json:"into"`
+	})
+
+	if err := utils.ReadJson(w, r, payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	name := tagParam(r)
+	payload.Into = models.NormalizeTag(payload.Into)
+
+	v := validator.New()
+
+	models.ValidateTagName(v, "into", payload.Into)
+	v.Check(payload.Into != name, "into", "cannot merge a tag into itself")
+
+	if !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	err := h.TagSvc.MergeTags(r.Context(), name, payload.Into)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("tag not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.SendStatus(w, http.StatusNoContent)
+}
+
+func tagParam(r *http.Request) string {
+	name, err := url.PathUnescape(chi.URLParam(r, "tag"))
+	if err != nil {
+		return ""
+	}
+
+	return models.NormalizeTag(name)
+}
@@ -2,58 +2,520 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"nexablog/internal/mailer"
 	"nexablog/internal/models"
 	"nexablog/internal/services"
+	"nexablog/internal/services/audit"
+	"nexablog/internal/services/lockout"
 	"nexablog/internal/services/token"
+	"nexablog/internal/services/totp"
 	"nexablog/internal/services/user"
+	"nexablog/internal/telemetry"
 	"nexablog/internal/utils"
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	activationTTL    = 72 * time.Hour
+	mfaChallengeTTL  = 5 * time.Minute
 )
 
 type Token struct {
-	UserSvc  user.Service
-	TokenSvc token.Service
+	UserSvc    user.Service
+	TokenSvc   token.Service
+	TOTPSvc    totp.Service
+	AuditSvc   audit.Service
+	LockoutSvc lockout.Service
+	Mailer     mailer.Mailer
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
 }
 
 func (h *Token) GetToken(w http.ResponseWriter, r *http.Request) error {
 	payload := new(struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
 	})
 
 	if err := utils.ReadJson(w, r, payload); err != nil {
 		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
 	}
 
+	if payload.MFAToken != "" {
+		return h.completeMFAChallenge(w, r, payload.MFAToken, payload.Code)
+	}
+
+	attempt := models.LoginAttempt{Email: payload.Email, IP: utils.ClientIP(r)}
+
+	reservation, err := h.reserveLogin(w, r, attempt)
+	if err != nil {
+		return err
+	}
+
 	user, err := h.UserSvc.FindUserByEmail(r.Context(), payload.Email)
 
 	if errors.Is(err, services.ErrResourceNotFound) {
+		h.auditFailedLogin(r, "email", payload.Email, "unknown_email")
+		h.failLogin(r, reservation, models.User{})
+
 		return utils.NewApiError("invalid credentials", http.StatusUnauthorized)
 	}
 
 	if err != nil {
-		return err
+		return h.abandonLogin(r, reservation, err)
 	}
 
 	isMatch, err := utils.PasswordMatch(user.Password, payload.Password)
 	if err != nil {
-		return err
+		return h.abandonLogin(r, reservation, err)
 	}
 
 	if !isMatch {
+		h.auditFailedLogin(r, "user", strconv.Itoa(user.UserID), "wrong_password")
+		h.failLogin(r, reservation, user)
+
 		return utils.NewApiError("invalid credentials", http.StatusUnauthorized)
 	}
 
+	if err := h.LockoutSvc.Release(r.Context(), reservation); err != nil {
+		return err
+	}
+
+	return h.login(w, r, user, "password")
+}
+
+// login answers a successful first factor with a token pair, or with an MFA
+// challenge when the user has two-factor authentication enabled.
+func (h *Token) login(w http.ResponseWriter, r *http.Request, user models.User, method string) error {
+	if user.TOTPEnabled {
+		challenge, err := h.TokenSvc.AddToken(r.Context(), models.TokenIn{
+			UserID:    user.UserID,
+			ExpiresAt: time.Now().Add(mfaChallengeTTL),
+			Scope:     models.ScopeMFA,
+		})
+		if err != nil {
+			return err
+		}
+
+		return utils.WriteJson(w, http.StatusOK, models.MFAChallengeOut{
+			MFARequired: true,
+			Token:       challenge.Plain,
+			ExpiresAt:   challenge.ExpiresAt,
+		})
+	}
+
+	family, err := utils.GenRandString(16)
+	if err != nil {
+		return err
+	}
+
+	pair, err := h.issueTokenPair(r, user.UserID, family)
+	if err != nil {
+		return err
+	}
+
+	if err := h.LockoutSvc.Reset(r.Context(), user.Email); err != nil {
+		return err
+	}
+
+	h.auditLogin(r, user.UserID, method)
+
+	return utils.WriteJson(w, http.StatusCreated, pair)
+}
+
+func (h *Token) completeMFAChallenge(
+	w http.ResponseWriter,
+	r *http.Request,
+	mfaToken, code string,
+) error {
+	v := validator.New()
+
+	if v.Check(lib.NonWhiteSpace(code), "code", "must be provided"); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	user, err := h.UserSvc.FindUserByToken(r.Context(), mfaToken, models.ScopeMFA)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("invalid or expired mfa token", http.StatusUnauthorized)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	attempt := models.LoginAttempt{Email: user.Email, IP: utils.ClientIP(r)}
+
+	// The challenge is consumed only once the attempt is let through, so that
+	// a refused attempt can be retried with it.
+	reservation, err := h.reserveLogin(w, r, attempt)
+	if err != nil {
+		return err
+	}
+
+	userID, err := h.TokenSvc.ConsumeToken(r.Context(), mfaToken, models.ScopeMFA)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return h.abandonLogin(
+			r,
+			reservation,
+			utils.NewApiError("invalid or expired mfa token", http.StatusUnauthorized),
+		)
+	}
+
+	if err != nil {
+		return h.abandonLogin(r, reservation, err)
+	}
+
+	err = h.TOTPSvc.Verify(r.Context(), userID, code)
+
+	if errors.Is(err, services.ErrInvalidCode) {
+		h.auditFailedLogin(r, "user", strconv.Itoa(userID), "invalid_code")
+		h.failLogin(r, reservation, user)
+
+		return utils.NewApiError("invalid code", http.StatusUnauthorized)
+	}
+
+	if err != nil {
+		return h.abandonLogin(r, reservation, err)
+	}
+
+	if err := h.LockoutSvc.Release(r.Context(), reservation); err != nil {
+		return err
+	}
+
+	family, err := utils.GenRandString(16)
+	if err != nil {
+		return err
+	}
+
+	pair, err := h.issueTokenPair(r, userID, family)
+	if err != nil {
+		return err
+	}
+
+	if err := h.LockoutSvc.Reset(r.Context(), user.Email); err != nil {
+		return err
+	}
+
+	h.auditLogin(r, userID, "totp")
+
+	return utils.WriteJson(w, http.StatusCreated, pair)
+}
+
+// reserveLogin counts the attempt as failed before its credentials are
+// checked, refusing it while the account or IP has to wait. Attempts that
+// succeed are given back with LockoutSvc.Release.
+func (h *Token) reserveLogin(
+	w http.ResponseWriter,
+	r *http.Request,
+	attempt models.LoginAttempt,
+) (models.LoginReservation, error) {
+	reservation, err := h.LockoutSvc.Reserve(r.Context(), attempt)
+	if err != nil {
+		return reservation, err
+	}
+
+	if reservation.Refused {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(reservation.RetryAfter.Seconds()))))
+		return reservation, utils.NewApiError(
+			"too many failed login attempts, try again later",
+			http.StatusTooManyRequests,
+		)
+	}
+
+	return reservation, nil
+}
+
+// abandonLogin gives back the reservation of an attempt that failed for a
+// reason other than its credentials, and returns err.
+func (h *Token) abandonLogin(r *http.Request, reservation models.LoginReservation, err error) error {
+	if releaseErr := h.LockoutSvc.Release(r.Context(), reservation); releaseErr != nil {
+		slog.ErrorContext(r.Context(), "could not release login reservation", "error", releaseErr)
+	}
+
+	return err
+}
+
+// failLogin tells the owner of an existing account by email when the failed
+// attempt locked it.
+func (h *Token) failLogin(r *http.Request, reservation models.LoginReservation, user models.User) {
+	if reservation.LockedUntil.IsZero() || user.UserID == 0 {
+		return
+	}
+
+	until := reservation.LockedUntil
+
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     models.AuditAccountLock,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.UserID),
+		After:      lib.H[any]{"locked_until": until},
+	})
+
+	sendMail(h.Mailer, lockoutMessage(user, reservation.Attempt.IP, until))
+}
+
+func (h *Token) auditLogin(r *http.Request, userID int, method string) {
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     models.AuditLogin,
+		ActorID:    userID,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		After:      lib.H[string]{"method": method},
+	})
+}
+
+func (h *Token) auditFailedLogin(r *http.Request, targetType, targetID, reason string) {
+	telemetry.LoginFailures.WithLabelValues(reason).Inc()
+
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     models.AuditLoginFailed,
+		TargetType: targetType,
+		TargetID:   targetID,
+		After:      lib.H[string]{"reason": reason},
+	})
+}
+
+func (h *Token) RefreshToken(w http.ResponseWriter, r *http.Request) error {
+	payload := new(struct {
+		RefreshToken string `json:"refresh_token"`
+	})
+
+	if err := utils.ReadJson(w, r, payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if v.Check(payload.RefreshToken != "", "refresh_token", "must be provided"); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	userID, family, err := h.TokenSvc.RotateRefreshToken(r.Context(), payload.RefreshToken)
+
+	if errors.Is(err, services.ErrTokenReused) {
+		recordAudit(h.AuditSvc, r, models.AuditIn{
+			Action:     models.AuditTokenRevoke,
+			TargetType: "session",
+			After:      lib.H[string]{"reason": "refresh token reuse"},
+		})
+		return utils.NewApiError("refresh token reuse detected", http.StatusUnauthorized)
+	}
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("invalid or expired refresh token", http.StatusUnauthorized)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	pair, err := h.issueTokenPair(r, userID, family)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusCreated, pair)
+}
+
+func (h *Token) issueTokenPair(r *http.Request, userID int, family string) (models.TokenPairOut, error) {
+	access, err := h.TokenSvc.AddToken(r.Context(), models.TokenIn{
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(h.AccessTTL),
+		Scope:     models.ScopeAuthentication,
+		Family:    family,
+		UserAgent: utils.UserAgent(r),
+		IP:        utils.ClientIP(r),
+	})
+	if err != nil {
+		return models.TokenPairOut{}, err
+	}
+
+	refresh, err := h.TokenSvc.AddToken(r.Context(), models.TokenIn{
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(h.RefreshTTL),
+		Scope:     models.ScopeRefresh,
+		Family:    family,
+		UserAgent: utils.UserAgent(r),
+		IP:        utils.ClientIP(r),
+	})
+	if err != nil {
+		return models.TokenPairOut{}, err
+	}
+
+	pair := models.TokenPairOut{
+		Plain:            access.Plain,
+		ExpiresAt:        access.ExpiresAt,
+		RefreshToken:     refresh.Plain,
+		RefreshExpiresAt: refresh.ExpiresAt,
+	}
+
+	return pair, nil
+}
+
+func (h *Token) RevokeCurrentToken(w http.ResponseWriter, r *http.Request) error {
+	err := h.TokenSvc.DeleteFamilyByHash(r.Context(), utils.GetTokenHash(r))
+
+	if err != nil && !errors.Is(err, services.ErrResourceNotFound) {
+		return err
+	}
+
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     models.AuditTokenRevoke,
+		TargetType: "session",
+		After:      lib.H[string]{"reason": "logout"},
+	})
+
+	return utils.SendStatus(w, http.StatusNoContent)
+}
+
+func (h *Token) RevokeAllTokens(w http.ResponseWriter, r *http.Request) error {
+	err := h.TokenSvc.DeleteAllForUser(
+		r.Context(),
+		utils.GetUser(r).UserID,
+		models.ScopeAuthentication,
+		models.ScopeRefresh,
+	)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     models.AuditTokenRevoke,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(utils.GetUser(r).UserID),
+		After:      lib.H[string]{"reason": "logout everywhere"},
+	})
+
+	return utils.SendStatus(w, http.StatusNoContent)
+}
+
+func (h *Token) RequestPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	payload := new(struct {
+		Email string `json:"email"`
+	})
+
+	if err := utils.ReadJson(w, r, payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if v.Check(lib.ValidEmail(payload.Email), "email", "provide a valid email"); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	accepted := lib.H[string]{
+		"detail": "if the email is registered, a password reset token has been sent to it",
+	}
+
+	user, err := h.UserSvc.FindUserByEmail(r.Context(), payload.Email)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.WriteJson(w, http.StatusAccepted, accepted)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = h.TokenSvc.DeleteAllForUser(r.Context(), user.UserID, models.ScopePasswordReset)
+	if err != nil {
+		return err
+	}
+
 	token, err := h.TokenSvc.AddToken(r.Context(), models.TokenIn{
 		UserID:    user.UserID,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-		Scope:     models.ScopeAuthentication,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+		Scope:     models.ScopePasswordReset,
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your nexablog password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Use the token below with PUT /api/users/password to choose a new password.\n\n"+
+				"%s\n\n"+
+				"The token expires at %s. If you did not ask for a reset, ignore this email.\n",
+			user.Username,
+			token.Plain,
+			token.ExpiresAt.UTC().Format(time.RFC1123),
+		),
+	}
+
+	sendMail(h.Mailer, msg)
+
+	return utils.WriteJson(w, http.StatusAccepted, accepted)
+}
+
+func (h *Token) RequestActivation(w http.ResponseWriter, r *http.Request) error {
+	payload := new(struct {
+		Email string `json:"email"`
+	})
+
+	if err := utils.ReadJson(w, r, payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if v.Check(lib.ValidEmail(payload.Email), "email", "provide a valid email"); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	accepted := lib.H[string]{
+		"detail": "if the email belongs to an inactive account, an activation token has been sent to it",
+	}
+
+	user, err := h.UserSvc.FindUserByEmail(r.Context(), payload.Email)
+
+	if errors.Is(err, services.ErrResourceNotFound) || (err == nil && user.Activated) {
+		return utils.WriteJson(w, http.StatusAccepted, accepted)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = h.TokenSvc.DeleteAllForUser(r.Context(), user.UserID, models.ScopeActivation)
+	if err != nil {
+		return err
+	}
+
+	token, err := h.TokenSvc.AddToken(r.Context(), models.TokenIn{
+		UserID:    user.UserID,
+		ExpiresAt: time.Now().Add(activationTTL),
+		Scope:     models.ScopeActivation,
 	})
 	if err != nil {
 		return err
 	}
 
-	return utils.WriteJson(w, http.StatusCreated, token)
+	sendMail(h.Mailer, activationMessage(user, token))
+
+	return utils.WriteJson(w, http.StatusAccepted, accepted)
 }
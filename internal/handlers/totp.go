@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nexablog/internal/models"
+	"nexablog/internal/services"
+	"nexablog/internal/services/totp"
+	"nexablog/internal/utils"
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
+
+type TOTP struct {
+	TOTPSvc totp.Service
+}
+
+func (h *TOTP) Enroll(w http.ResponseWriter, r *http.Request) error {
+	enrollment, err := h.TOTPSvc.Enroll(r.Context(), *utils.GetUser(r))
+
+	if errors.Is(err, services.ErrUpdateConflict) {
+		return utils.NewApiError("two-factor authentication is already enabled", http.StatusConflict)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusCreated, enrollment)
+}
+
+func (h *TOTP) Confirm(w http.ResponseWriter, r *http.Request) error {
+	payload := models.TOTPCodeIn{}
+
+	if err := utils.ReadJson(w, r, &payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if models.ValidateTOTPCode(v, &payload); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	codes, err := h.TOTPSvc.Confirm(r.Context(), utils.GetUser(r).UserID, payload.Code)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("two-factor enrollment has not been started", http.StatusNotFound)
+	}
+
+	if errors.Is(err, services.ErrUpdateConflict) {
+		return utils.NewApiError("two-factor authentication is already enabled", http.StatusConflict)
+	}
+
+	if errors.Is(err, services.ErrInvalidCode) {
+		return utils.NewApiError("invalid code", http.StatusUnprocessableEntity)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, models.RecoveryCodesOut{RecoveryCodes: codes})
+}
+
+func (h *TOTP) Disable(w http.ResponseWriter, r *http.Request) error {
+	user := utils.GetUser(r)
+
+	if !user.TOTPEnabled {
+		return utils.NewApiError("two-factor authentication is not enabled", http.StatusConflict)
+	}
+
+	payload := models.TOTPCodeIn{}
+
+	if err := utils.ReadJson(w, r, &payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if models.ValidateTOTPCode(v, &payload); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	err := h.TOTPSvc.Disable(r.Context(), user.UserID, payload.Code)
+
+	if errors.Is(err, services.ErrInvalidCode) {
+		return utils.NewApiError("invalid code", http.StatusUnprocessableEntity)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.SendStatus(w, http.StatusNoContent)
+}
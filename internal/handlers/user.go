@@ -3,11 +3,18 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+
+	"nexablog/internal/mailer"
 	"nexablog/internal/models"
 	"nexablog/internal/services"
+	"nexablog/internal/services/audit"
 	"nexablog/internal/services/permission"
 	"nexablog/internal/services/post"
+	"nexablog/internal/services/token"
 	"nexablog/internal/services/user"
 	"nexablog/internal/utils"
 	"nexablog/pkg/lib"
@@ -18,6 +25,9 @@ type User struct {
 	UserSvc       user.Service
 	PermissionSvc permission.Service
 	PostSvc       post.Service
+	TokenSvc      token.Service
+	AuditSvc      audit.Service
+	Mailer        mailer.Mailer
 }
 
 func (h *User) Register(w http.ResponseWriter, r *http.Request) error {
@@ -45,20 +55,67 @@ func (h *User) Register(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	err = h.PermissionSvc.AddUserPermission(
-		r.Context(),
-		user.UserID,
-		"posts:read",
-		"posts:write",
-	)
+	err = h.PermissionSvc.AddUserRole(r.Context(), user.UserID, models.RoleReader)
+	if err != nil {
+		return err
+	}
 
+	token, err := h.TokenSvc.AddToken(r.Context(), models.TokenIn{
+		UserID:    user.UserID,
+		ExpiresAt: time.Now().Add(activationTTL),
+		Scope:     models.ScopeActivation,
+	})
 	if err != nil {
 		return err
 	}
 
+	sendMail(h.Mailer, activationMessage(user, token))
+
 	return utils.WriteJson(w, http.StatusCreated, user)
 }
 
+func (h *User) Activate(w http.ResponseWriter, r *http.Request) error {
+	payload := new(struct {
+		Token string `json:"token"`
+	})
+
+	if err := utils.ReadJson(w, r, payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if v.Check(lib.NonWhiteSpace(payload.Token), "token", "must be provided"); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	userID, err := h.TokenSvc.ConsumeToken(r.Context(), payload.Token, models.ScopeActivation)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": lib.H[string]{"token": "invalid or expired activation token"},
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	user, err := h.UserSvc.ActivateUser(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	err = h.PermissionSvc.AddUserRole(r.Context(), user.UserID, models.RoleAuthor)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, user)
+}
+
 func (h *User) GetMe(w http.ResponseWriter, r *http.Request) error {
 	user := utils.GetUser(r)
 
@@ -72,3 +129,149 @@ func (h *User) GetMe(w http.ResponseWriter, r *http.Request) error {
 		"posts": posts,
 	})
 }
+
+func (h *User) ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	payload := models.PasswordResetIn{}
+
+	if err := utils.ReadJson(w, r, &payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	v := validator.New()
+
+	if models.ValidatePasswordReset(v, &payload); !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	err := h.UserSvc.ResetPassword(r.Context(), payload.Token, payload.Password)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": lib.H[string]{"token": "invalid or expired password reset token"},
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, lib.H[string]{
+		"detail": "your password was reset successfully",
+	})
+}
+
+func (h *User) FindSessions(w http.ResponseWriter, r *http.Request) error {
+	sessions, err := h.TokenSvc.FindSessionsByUser(
+		r.Context(),
+		utils.GetUser(r).UserID,
+		utils.GetTokenHash(r),
+	)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, sessions)
+}
+
+func (h *User) DeleteSession(w http.ResponseWriter, r *http.Request) error {
+	sessionID := chi.URLParam(r, "session-id")
+
+	err := h.TokenSvc.DeleteSession(r.Context(), utils.GetUser(r).UserID, sessionID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("session not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     models.AuditTokenRevoke,
+		TargetType: "session",
+		TargetID:   sessionID,
+	})
+
+	return utils.SendStatus(w, http.StatusNoContent)
+}
+
+func (h *User) FindPersonalTokens(w http.ResponseWriter, r *http.Request) error {
+	tokens, err := h.TokenSvc.FindPersonalTokens(r.Context(), utils.GetUser(r).UserID)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJson(w, http.StatusOK, tokens)
+}
+
+func (h *User) CreatePersonalToken(w http.ResponseWriter, r *http.Request) error {
+	payload := models.PersonalTokenIn{}
+
+	if err := utils.ReadJson(w, r, &payload); err != nil {
+		return utils.NewApiError(err.Error(), http.StatusUnprocessableEntity)
+	}
+
+	user := utils.GetUser(r)
+
+	permissions, err := h.PermissionSvc.GetUserPermission(r.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+
+	models.ValidatePersonalToken(v, &payload)
+
+	for _, code := range payload.Permissions {
+		v.Check(permissions.Include(code), "permissions", code+" is not one of your permissions")
+	}
+
+	if !v.Valid() {
+		return utils.WriteJson(w, http.StatusUnprocessableEntity, lib.H[any]{
+			"details": v.Errors,
+		})
+	}
+
+	token, err := h.TokenSvc.AddPersonalToken(r.Context(), user.UserID, payload)
+
+	if errors.Is(err, services.ErrDuplicateKey) {
+		return utils.NewApiError("a token with this name already exists", http.StatusConflict)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     models.AuditTokenCreate,
+		TargetType: "personal_token",
+		TargetID:   strconv.Itoa(token.TokenID),
+		After:      token.PersonalToken,
+	})
+
+	return utils.WriteJson(w, http.StatusCreated, token)
+}
+
+func (h *User) DeletePersonalToken(w http.ResponseWriter, r *http.Request) error {
+	tokenID, _ := strconv.Atoi(chi.URLParam(r, "token-id"))
+
+	err := h.TokenSvc.DeletePersonalToken(r.Context(), utils.GetUser(r).UserID, tokenID)
+
+	if errors.Is(err, services.ErrResourceNotFound) {
+		return utils.NewApiError("token not found", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	recordAudit(h.AuditSvc, r, models.AuditIn{
+		Action:     models.AuditTokenRevoke,
+		TargetType: "personal_token",
+		TargetID:   strconv.Itoa(tokenID),
+	})
+
+	return utils.SendStatus(w, http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"nexablog/internal/utils"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+
+	if level == "" {
+		level = "info"
+	}
+
+	if err := lvl.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+
+	switch format {
+	case FormatText, "":
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return slog.New(&requestHandler{h}), nil
+}
+
+// requestHandler adds the request ID and user ID of the request, and the
+// trace ID of the current span, if any, to every record logged with its
+// context.
+type requestHandler struct {
+	slog.Handler
+}
+
+func (h *requestHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if info := utils.GetRequestInfo(ctx); info != nil {
+		rec.AddAttrs(slog.String("request_id", info.ID))
+
+		if info.UserID != 0 {
+			rec.AddAttrs(slog.Int("user_id", info.UserID))
+		}
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		rec.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h *requestHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *requestHandler) WithGroup(name string) slog.Handler {
+	return &requestHandler{h.Handler.WithGroup(name)}
+}
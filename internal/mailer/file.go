@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"nexablog/internal/utils"
+)
+
+type fileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from, dir string) (Mailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mail directory is required for the file mailer")
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &fileMailer{from, dir}, nil
+}
+
+func (m *fileMailer) Send(_ context.Context, msg Message) error {
+	suffix, err := utils.GenRandString(5)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), suffix)
+
+	return os.WriteFile(filepath.Join(m.dir, name), encode(m.from, msg), 0o640)
+}
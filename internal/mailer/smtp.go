@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+type smtpMailer struct {
+	from   string
+	sender string
+	addr   string
+	auth   smtp.Auth
+}
+
+func NewSMTPMailer(from, host string, port int, username, password string) (Mailer, error) {
+	if host == "" {
+		return nil, fmt.Errorf("smtp host is required for the smtp mailer")
+	}
+
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	m := &smtpMailer{
+		from:   from,
+		sender: address.Address,
+		addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+		auth:   auth,
+	}
+
+	return m, nil
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	errch := make(chan error, 1)
+
+	go func() {
+		errch <- smtp.SendMail(m.addr, m.auth, m.sender, []string{msg.To}, encode(m.from, msg))
+	}()
+
+	select {
+	case err := <-errch:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"nexablog/pkg/validator"
+)
+
+type AuditAction string
+
+const (
+	AuditLogin            AuditAction = "login"
+	AuditLoginFailed      AuditAction = "login.failed"
+	AuditAccountLock      AuditAction = "account.lock"
+	AuditTokenCreate      AuditAction = "token.create"
+	AuditTokenRevoke      AuditAction = "token.revoke"
+	AuditRoleGrant        AuditAction = "role.grant"
+	AuditRoleRevoke       AuditAction = "role.revoke"
+	AuditPermissionGrant  AuditAction = "permission.grant"
+	AuditPermissionRevoke AuditAction = "permission.revoke"
+	AuditPostCreate       AuditAction = "post.create"
+	AuditPostUpdate       AuditAction = "post.update"
+	AuditPostDelete       AuditAction = "post.delete"
+)
+
+type AuditEntry struct {
+	AuditID    int64           `json:"audit_id"`
+	Action     AuditAction     `json:"action"`
+	ActorID    *int            `json:"actor_id"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type AuditEntries []AuditEntry
+
+type AuditIn struct {
+	Action     AuditAction
+	ActorID    int
+	TargetType string
+	TargetID   string
+	IP         string
+	UserAgent  string
+	Before     any
+	After      any
+}
+
+type AuditCursor struct {
+	AuditID int64 `json:"i"`
+}
+
+type AuditFilter struct {
+	Limit      int
+	Cursor     string
+	Action     string
+	ActorID    int
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	After      *AuditCursor
+}
+
+func ValidateAuditFilter(v *validator.Validator, f *AuditFilter) {
+	v.Check(f.Limit >= 1, "limit", "must be greater than zero")
+	v.Check(f.Limit <= 100, "limit", "must be a maximum of 100")
+	v.Check(f.ActorID >= 0, "actor_id", "must be a positive integer")
+	v.Check(
+		f.Since.IsZero() || f.Until.IsZero() || f.Since.Before(f.Until),
+		"since",
+		"must be before until",
+	)
+
+	if f.Cursor == "" {
+		return
+	}
+
+	cursor := &AuditCursor{}
+
+	if err := DecodeCursor(f.Cursor, cursor); err != nil {
+		v.Check(false, "cursor", "is invalid")
+		return
+	}
+
+	f.After = cursor
+}
+
+type AuditPage struct {
+	Entries    AuditEntries `json:"entries"`
+	NextCursor string       `json:"next_cursor"`
+	Metadata   Metadata     `json:"metadata"`
+}
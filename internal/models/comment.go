@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
+
+type Comment struct {
+	CommentID  int        `json:"comment_id"`
+	PostID     int        `json:"post_id"`
+	ParentID   *int       `json:"parent_id"`
+	AuthorID   int        `json:"author_id"`
+	Body       string     `json:"body"`
+	Deleted    bool       `json:"deleted"`
+	ReplyCount int        `json:"reply_count"`
+	Version    int        `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+type Comments []Comment
+
+type CommentIn struct {
+	Body     string `json:"body"`
+	ParentID *int   `json:"parent_id"`
+	PostID   int    `json:"-"`
+	AuthorID int    `json:"-"`
+}
+
+func ValidateComment(v *validator.Validator, c *CommentIn) {
+	v.Check(lib.NonWhiteSpace(c.Body), "body", "cannot be blank")
+	v.Check(utf8.RuneCountInString(c.Body) <= 10_000, "body", "must not be more than 10000 characters")
+	v.Check(c.ParentID == nil || *c.ParentID > 0, "parent_id", "must be a positive integer")
+}
+
+type CommentCursor struct {
+	CommentID int `json:"i"`
+}
+
+type CommentFilter struct {
+	PostID   int
+	ParentID int
+	Limit    int
+	Cursor   string
+	After    *CommentCursor
+}
+
+func ValidateCommentFilter(v *validator.Validator, f *CommentFilter) {
+	v.Check(f.Limit >= 1, "limit", "must be greater than zero")
+	v.Check(f.Limit <= 100, "limit", "must be a maximum of 100")
+
+	if f.Cursor == "" {
+		return
+	}
+
+	cursor := &CommentCursor{}
+
+	if err := DecodeCursor(f.Cursor, cursor); err != nil {
+		v.Check(false, "cursor", "is invalid")
+		return
+	}
+
+	f.After = cursor
+}
+
+type CommentPage struct {
+	Comments   Comments `json:"comments"`
+	NextCursor string   `json:"next_cursor"`
+	Metadata   Metadata `json:"metadata"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type FeedState struct {
+	LastModified time.Time
+	Count        int
+	LatestID     int
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type Identity struct {
+	Provider  string
+	Subject   string
+	UserID    int
+	Email     string
+	CreatedAt time.Time
+}
+
+type OIDCState struct {
+	Hash         []byte
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type LoginAttempt struct {
+	Email string
+	IP    string
+}
+
+// LoginReservation is a login attempt counted as failed before its
+// credentials are checked. A refused attempt must wait RetryAfter.
+// LockedUntil is set when the attempt locks the account should it fail.
+type LoginReservation struct {
+	Attempt           LoginAttempt
+	Refused           bool
+	RetryAfter        time.Duration
+	LockedUntil       time.Time
+	IPReservedAt      time.Time
+	AccountReservedAt time.Time
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"nexablog/pkg/validator"
+)
+
+type ModerationAction string
+
+const (
+	ModerationUpdate ModerationAction = "update"
+	ModerationDelete ModerationAction = "delete"
+)
+
+type Moderation struct {
+	ModerationID int              `json:"moderation_id"`
+	PostID       int              `json:"post_id"`
+	AuthorID     *int             `json:"author_id"`
+	ModeratorID  *int             `json:"moderator_id"`
+	Action       ModerationAction `json:"action"`
+	Reason       string           `json:"reason"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+type Moderations []Moderation
+
+type ModerationIn struct {
+	PostID      int
+	AuthorID    int
+	ModeratorID int
+	Action      ModerationAction
+	Reason      string
+}
+
+type ModerationFilter struct {
+	PostID int
+	Limit  int
+}
+
+func ValidateModerationFilter(v *validator.Validator, f *ModerationFilter) {
+	v.Check(f.PostID >= 0, "post_id", "must be a positive integer")
+	v.Check(f.Limit >= 1, "limit", "must be greater than zero")
+	v.Check(f.Limit <= 100, "limit", "must be a maximum of 100")
+}
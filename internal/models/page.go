@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type Metadata struct {
+	Limit   int    `json:"limit"`
+	Count   int    `json:"count"`
+	Sort    string `json:"sort,omitempty"`
+	Order   string `json:"order,omitempty"`
+	HasMore bool   `json:"has_more"`
+}
+
+func EncodeCursor(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
@@ -7,6 +7,36 @@ type Permission struct {
 
 type Permissions []Permission
 
+const (
+	RoleReader = "reader"
+	RoleAuthor = "author"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+type Roles []Role
+
+func (r Roles) Include(name string) bool {
+	for idx := range r {
+		if r[idx].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type UserAccess struct {
+	UserID      int      `json:"user_id"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	Effective   []string `json:"effective_permissions"`
+}
+
 func (p Permissions) Include(code string) bool {
 	for idx := range p {
 		if p[idx].Code == code {
@@ -15,3 +45,28 @@ func (p Permissions) Include(code string) bool {
 	}
 	return false
 }
+
+func (p Permissions) Intersect(codes []string) Permissions {
+	permissions := make(Permissions, 0, len(p))
+
+	for idx := range p {
+		for _, code := range codes {
+			if p[idx].Code == code {
+				permissions = append(permissions, p[idx])
+				break
+			}
+		}
+	}
+
+	return permissions
+}
+
+func (p Permissions) Codes() []string {
+	codes := make([]string, 0, len(p))
+
+	for idx := range p {
+		codes = append(codes, p[idx].Code)
+	}
+
+	return codes
+}
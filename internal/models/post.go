@@ -7,24 +7,174 @@ import (
 	"nexablog/pkg/validator"
 )
 
+const (
+	PostSortCreatedAt = "created_at"
+	PostSortPostID    = "post_id"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+type PostStatus string
+
+const (
+	PostStatusDraft     PostStatus = "draft"
+	PostStatusPublished PostStatus = "published"
+	PostStatusScheduled PostStatus = "scheduled"
+	PostStatusArchived  PostStatus = "archived"
+)
+
+func (s PostStatus) Valid() bool {
+	switch s {
+	case PostStatusDraft, PostStatusPublished, PostStatusScheduled, PostStatusArchived:
+		return true
+	}
+	return false
+}
+
 type Post struct {
-	PostID    int       `json:"post_id"`
-	Title     string    `json:"title"`
-	Body      string    `json:"body"`
-	AuthorID  int       `json:"-"`
-	Version   int       `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
+	PostID      int        `json:"post_id"`
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug"`
+	Body        string     `json:"body"`
+	Status      PostStatus `json:"status"`
+	PublishedAt *time.Time `json:"published_at"`
+	Tags        []string   `json:"tags"`
+	AuthorID    int        `json:"-"`
+	Version     int        `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (p *Post) VisibleTo(u *User) bool {
+	return p.Status == PostStatusPublished || u.IsOwner(p.AuthorID)
 }
 
 type Posts []Post
 
 type PostIn struct {
-	Title    string `json:"title"`
-	Body     string `json:"body"`
-	AuthorID int    `json:"-"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	Status      PostStatus `json:"status"`
+	PublishedAt *time.Time `json:"published_at"`
+	Tags        *[]string  `json:"tags"`
+	Slug        string     `json:"-"`
+	AuthorID    int        `json:"-"`
+	EditorID    int        `json:"-"`
+	// keptSchedule is set when PublishedAt was carried over from the stored
+	// post rather than requested.
+	keptSchedule bool
+}
+
+// SetTags normalizes the requested tags, keeping current when the request
+// left the tags field out.
+func (p *PostIn) SetTags(current []string) {
+	tags := current
+	if p.Tags != nil {
+		tags = NormalizeTags(*p.Tags)
+	}
+	p.Tags = &tags
+}
+
+// SetPublishedAt keeps the current publication time when the request changes
+// neither published_at nor the status.
+func (p *PostIn) SetPublishedAt(current Post) {
+	if p.PublishedAt == nil && p.Status == current.Status {
+		p.PublishedAt = current.PublishedAt
+		p.keptSchedule = true
+	}
 }
 
 func ValidatePost(v *validator.Validator, p *PostIn) {
 	v.Check(lib.NonWhiteSpace(p.Title), "title", "cannot be blank")
 	v.Check(lib.NonWhiteSpace(p.Body), "body", "cannot be blank")
+	v.Check(
+		p.Status.Valid(),
+		"status",
+		"must be one of draft, published, scheduled, archived",
+	)
+
+	if p.Tags != nil {
+		v.Check(len(*p.Tags) <= 10, "tags", "must not contain more than 10 tags")
+
+		for _, tag := range *p.Tags {
+			ValidateTagName(v, "tags", tag)
+		}
+	}
+
+	if p.Status == PostStatusScheduled {
+		v.Check(p.PublishedAt != nil, "published_at", "must be provided for scheduled posts")
+		v.Check(
+			p.PublishedAt == nil || p.keptSchedule || p.PublishedAt.After(time.Now()),
+			"published_at",
+			"must be in the future for scheduled posts",
+		)
+	}
+}
+
+type PostCursor struct {
+	Sort      string    `json:"s"`
+	Order     string    `json:"o"`
+	PostID    int       `json:"i"`
+	CreatedAt time.Time `json:"c"`
+}
+
+type PostFilter struct {
+	Limit         int
+	Sort          string
+	Order         string
+	Cursor        string
+	AuthorID      int
+	Status        PostStatus
+	Tag           string
+	ViewerID      int
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	After         *PostCursor
+}
+
+func ValidatePostFilter(v *validator.Validator, f *PostFilter) {
+	v.Check(f.Limit >= 1, "limit", "must be greater than zero")
+	v.Check(f.Limit <= 100, "limit", "must be a maximum of 100")
+	v.Check(
+		f.Sort == PostSortCreatedAt || f.Sort == PostSortPostID,
+		"sort",
+		"must be one of created_at, post_id",
+	)
+	v.Check(f.Order == OrderAsc || f.Order == OrderDesc, "order", "must be one of asc, desc")
+	v.Check(f.AuthorID >= 0, "author_id", "must be a positive integer")
+	v.Check(
+		f.Status == "" || f.Status.Valid(),
+		"status",
+		"must be one of draft, published, scheduled, archived",
+	)
+	v.Check(
+		f.CreatedAfter.IsZero() || f.CreatedBefore.IsZero() || f.CreatedAfter.Before(f.CreatedBefore),
+		"created_after",
+		"must be before created_before",
+	)
+
+	if f.Cursor == "" {
+		return
+	}
+
+	cursor := &PostCursor{}
+
+	if err := DecodeCursor(f.Cursor, cursor); err != nil {
+		v.Check(false, "cursor", "is invalid")
+		return
+	}
+
+	v.Check(
+		cursor.Sort == f.Sort && cursor.Order == f.Order,
+		"cursor",
+		"does not match sort and order",
+	)
+
+	f.After = cursor
+}
+
+type PostPage struct {
+	Posts      Posts    `json:"posts"`
+	NextCursor string   `json:"next_cursor"`
+	Metadata   Metadata `json:"metadata"`
 }
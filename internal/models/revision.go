@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"nexablog/pkg/diff"
+)
+
+type Revision struct {
+	PostID    int       `json:"post_id"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	EditorID  *int      `json:"editor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Revisions []Revision
+
+type RevisionDiff struct {
+	PostID int         `json:"post_id"`
+	From   int         `json:"from"`
+	To     int         `json:"to"`
+	Title  []diff.Line `json:"title"`
+	Body   []diff.Line `json:"body"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"nexablog/pkg/validator"
+)
+
+type SearchResult struct {
+	Post
+	Rank           float32 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+type SearchResults []SearchResult
+
+type SearchCursor struct {
+	Rank   float32 `json:"r"`
+	PostID int     `json:"i"`
+}
+
+type PostSearch struct {
+	Query    string
+	Limit    int
+	Cursor   string
+	ViewerID int
+	After    *SearchCursor
+}
+
+func (s *PostSearch) Terms() []string {
+	terms := strings.FieldsFunc(s.Query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(terms) > 10 {
+		terms = terms[:10]
+	}
+
+	return terms
+}
+
+func (s *PostSearch) TSQuery() string {
+	terms := s.Terms()
+
+	for i := range terms {
+		terms[i] = strings.ToLower(terms[i]) + ":*"
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+func ValidatePostSearch(v *validator.Validator, s *PostSearch) {
+	v.Check(utf8.RuneCountInString(s.Query) <= 200, "q", "must not be more than 200 characters")
+	v.Check(len(s.Terms()) > 0, "q", "must contain at least one word")
+	v.Check(s.Limit >= 1, "limit", "must be greater than zero")
+	v.Check(s.Limit <= 100, "limit", "must be a maximum of 100")
+
+	if s.Cursor == "" {
+		return
+	}
+
+	cursor := &SearchCursor{}
+
+	if err := DecodeCursor(s.Cursor, cursor); err != nil {
+		v.Check(false, "cursor", "is invalid")
+		return
+	}
+
+	s.After = cursor
+}
+
+type SearchPage struct {
+	Results    SearchResults `json:"results"`
+	NextCursor string        `json:"next_cursor"`
+	Metadata   Metadata      `json:"metadata"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"nexablog/pkg/validator"
+)
+
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}]+(-[\p{L}\p{N}]+)*$`)
+
+type Tag struct {
+	TagID     int    `json:"-"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+}
+
+type Tags []Tag
+
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+func ValidTagName(tag string) bool {
+	return utf8.RuneCountInString(tag) <= 32 && tagPattern.MatchString(tag)
+}
+
+func ValidateTagName(v *validator.Validator, field, tag string) {
+	v.Check(
+		ValidTagName(tag),
+		field,
+		"must only contain letters, digits and hyphens (max 32 characters)",
+	)
+}
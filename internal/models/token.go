@@ -1,27 +1,94 @@
 package models
 
-import "time"
+import (
+	"time"
+	"unicode/utf8"
+
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
 
 type Scope string
 
 const (
 	ScopeAuthentication Scope = "authentication"
+	ScopePasswordReset  Scope = "password-reset"
+	ScopeActivation     Scope = "activation"
+	ScopeRefresh        Scope = "refresh"
+	ScopePersonal       Scope = "personal"
+	ScopeMFA            Scope = "mfa"
 )
 
+const PersonalTokenPrefix = "nxb_"
+
 type Token struct {
 	Hash      []byte
 	UserID    int
 	ExpiresAt time.Time
 	Scope     Scope
+	Family    string
+	UserAgent string
+	IP        string
 }
 
 type TokenIn struct {
 	UserID    int
 	ExpiresAt time.Time
 	Scope     Scope
+	Family    string
+	UserAgent string
+	IP        string
 }
 
 type TokenOut struct {
 	Plain     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
+
+type TokenPairOut struct {
+	Plain            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type Session struct {
+	SessionID  string     `json:"session_id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	Current    bool       `json:"current"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+type Sessions []Session
+
+type PersonalToken struct {
+	TokenID     int        `json:"token_id"`
+	Name        string     `json:"name"`
+	Permissions []string   `json:"permissions"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type PersonalTokens []PersonalToken
+
+type PersonalTokenIn struct {
+	Name        string     `json:"name"`
+	Permissions []string   `json:"permissions"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type PersonalTokenOut struct {
+	PersonalToken
+	Plain string `json:"token"`
+}
+
+func ValidatePersonalToken(v *validator.Validator, t *PersonalTokenIn) {
+	v.Check(lib.NonWhiteSpace(t.Name), "name", "cannot be blank")
+	v.Check(utf8.RuneCountInString(t.Name) <= 100, "name", "must not be more than 100 characters")
+	v.Check(len(t.Permissions) > 0, "permissions", "must contain at least one permission")
+	v.Check(t.ExpiresAt == nil || t.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"nexablog/pkg/lib"
+	"nexablog/pkg/validator"
+)
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TOTPCodeIn struct {
+	Code string `json:"code"`
+}
+
+func ValidateTOTPCode(v *validator.Validator, c *TOTPCodeIn) {
+	v.Check(lib.NonWhiteSpace(c.Code), "code", "must be provided")
+}
+
+type RecoveryCodesOut struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAChallengeOut struct {
+	MFARequired bool      `json:"mfa_required"`
+	Token       string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.Join(strings.Fields(code), "")
+}
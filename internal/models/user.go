@@ -8,12 +8,14 @@ import (
 )
 
 type User struct {
-	UserID    int       `json:"user_id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  []byte    `json:"-"`
-	Version   int       `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
+	UserID      int       `json:"user_id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	Password    []byte    `json:"-"`
+	Activated   bool      `json:"activated"`
+	TOTPEnabled bool      `json:"totp_enabled"`
+	Version     int       `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 var AnonymousUser = &User{}
@@ -43,7 +45,22 @@ type UserIn struct {
 func ValidateUser(v *validator.Validator, u *UserIn) {
 	v.Check(lib.NonWhiteSpace(u.Username), "username", "cannot be blank")
 	v.Check(lib.NonWhiteSpace(u.Email), "email", "cannot be blank")
-	v.Check(lib.NonWhiteSpace(u.Password), "password", "cannot be blank")
 	v.Check(lib.ValidEmail(u.Email), "email", "provide a valid email")
-	v.Check(len(u.Password) >= 8, "password", "must be at least 8 characters")
+	ValidatePassword(v, u.Password)
+}
+
+func ValidatePassword(v *validator.Validator, password string) {
+	v.Check(lib.NonWhiteSpace(password), "password", "cannot be blank")
+	v.Check(len(password) >= 8, "password", "must be at least 8 characters")
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes")
+}
+
+type PasswordResetIn struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func ValidatePasswordReset(v *validator.Validator, p *PasswordResetIn) {
+	v.Check(lib.NonWhiteSpace(p.Token), "token", "must be provided")
+	ValidatePassword(v, p.Password)
 }
@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"nexablog/internal/models"
+	"nexablog/internal/utils"
+)
+
+var ErrForbidden = errors.New("not allowed")
+
+type PermissionFinder interface {
+	GetUserPermission(context.Context, int) (models.Permissions, error)
+}
+
+type Policy struct {
+	finder PermissionFinder
+}
+
+func New(finder PermissionFinder) *Policy {
+	return &Policy{finder}
+}
+
+// Permissions returns what the request may do: the user's permissions,
+// narrowed to the token's scopes when a personal access token was used.
+func (p *Policy) Permissions(r *http.Request) (models.Permissions, error) {
+	user := utils.GetUser(r)
+
+	if user.IsAnonymousUser() {
+		return make(models.Permissions, 0), nil
+	}
+
+	permissions, err := p.finder.GetUserPermission(r.Context(), user.UserID)
+	if err != nil {
+		return make(models.Permissions, 0), err
+	}
+
+	if codes := utils.GetTokenPermissions(r); codes != nil {
+		permissions = permissions.Intersect(codes)
+	}
+
+	return permissions, nil
+}
+
+func (p *Policy) Can(r *http.Request, code string) (bool, error) {
+	permissions, err := p.Permissions(r)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.Include(code), nil
+}
+
+// OwnerOr allows the owner of a resource or anyone holding code. The
+// returned bool reports whether access was granted only through code.
+func (p *Policy) OwnerOr(r *http.Request, ownerID int, code string) (bool, error) {
+	if utils.GetUser(r).IsOwner(ownerID) {
+		return false, nil
+	}
+
+	allowed, err := p.Can(r, code)
+	if err != nil {
+		return false, err
+	}
+
+	if !allowed {
+		return false, ErrForbidden
+	}
+
+	return true, nil
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestTake(t *testing.T) {
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    Result
+	}{
+		{"first", 0, Result{Allowed: true, Remaining: 2, Reset: time.Second}},
+		{"second", 0, Result{Allowed: true, Remaining: 1, Reset: 2 * time.Second}},
+		{"third", 0, Result{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+		{"empty", 0, Result{Remaining: 0, Reset: 3 * time.Second, RetryAfter: time.Second}},
+		{"half refilled", 500 * time.Millisecond, Result{
+			Remaining:  0,
+			Reset:      2500 * time.Millisecond,
+			RetryAfter: 500 * time.Millisecond,
+		}},
+		{"refilled one", 500 * time.Millisecond, Result{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+		{"refilled fully", time.Hour, Result{Allowed: true, Remaining: 2, Reset: time.Second}},
+	}
+
+	c := &clock{time.Unix(0, 0)}
+	s := newMemoryStore(c.now)
+
+	for _, tt := range tests {
+		c.advance(tt.advance)
+
+		got, err := s.Take(context.Background(), "key", limit)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: Take = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTakeKeepsKeysApart(t *testing.T) {
+	limit := Limit{Requests: 1, Period: time.Minute}
+
+	c := &clock{time.Unix(0, 0)}
+	s := newMemoryStore(c.now)
+
+	if got, _ := s.Take(context.Background(), "a", limit); !got.Allowed {
+		t.Fatal("first take from a was refused")
+	}
+
+	if got, _ := s.Take(context.Background(), "b", limit); !got.Allowed {
+		t.Error("take from b was refused after a emptied")
+	}
+
+	if got, _ := s.Take(context.Background(), "a", limit); got.Allowed {
+		t.Error("second take from a was allowed")
+	}
+}
+
+func TestTakeRebuildsBucketWhenLimitChanges(t *testing.T) {
+	c := &clock{time.Unix(0, 0)}
+	s := newMemoryStore(c.now)
+
+	small := Limit{Requests: 1, Period: time.Minute}
+
+	s.Take(context.Background(), "key", small)
+
+	if got, _ := s.Take(context.Background(), "key", small); got.Allowed {
+		t.Fatal("take from an empty bucket was allowed")
+	}
+
+	got, _ := s.Take(context.Background(), "key", Limit{Requests: 5, Period: time.Minute})
+
+	if !got.Allowed || got.Remaining != 4 {
+		t.Errorf("Take with a new limit = %+v, want allowed with 4 remaining", got)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	limit := Limit{Requests: 2, Period: 10 * time.Minute}
+
+	c := &clock{time.Unix(0, 0)}
+	s := newMemoryStore(c.now)
+
+	s.Take(context.Background(), "full", limit)
+	s.Take(context.Background(), "drained", limit)
+	s.Take(context.Background(), "drained", limit)
+
+	// After one sweep interval "full" has refilled its token, while
+	// "drained" still misses one.
+	c.advance(sweepInterval + 4*time.Minute)
+	s.Take(context.Background(), "other", limit)
+
+	if _, ok := s.buckets["full"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+
+	if _, ok := s.buckets["drained"]; !ok {
+		t.Error("bucket that has not refilled was swept")
+	}
+}
+
+func TestSweepWaitsForInterval(t *testing.T) {
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	c := &clock{time.Unix(0, 0)}
+	s := newMemoryStore(c.now)
+
+	s.Take(context.Background(), "key", limit)
+
+	c.advance(sweepInterval / 2)
+	s.Take(context.Background(), "other", limit)
+
+	if _, ok := s.buckets["key"]; !ok {
+		t.Error("bucket was swept before the sweep interval")
+	}
+}
@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"nexablog/internal/models"
+	"nexablog/internal/utils"
+)
+
+type Repo interface {
+	CreateEntry(context.Context, models.AuditIn, []byte, []byte) error
+	FindEntries(context.Context, models.AuditFilter) (models.AuditEntries, error)
+}
+
+type repo struct {
+	db utils.DBTX
+}
+
+func NewRepo(db utils.DBTX) Repo {
+	return &repo{
+		db,
+	}
+}
+
+func (r *repo) CreateEntry(
+	ctx context.Context,
+	entry models.AuditIn,
+	before, after []byte,
+) error {
+	q := `
+  INSERT INTO audit_log 
+  (action, actor_id, target_type, target_id, ip, user_agent, before, after)
+  VALUES ($1, NULLIF($2::int, 0), $3, $4, $5, $6, $7, $8);
+  `
+
+	_, err := r.db.ExecContext(
+		ctx,
+		q,
+		entry.Action,
+		entry.ActorID,
+		entry.TargetType,
+		entry.TargetID,
+		entry.IP,
+		entry.UserAgent,
+		jsonParam(before),
+		jsonParam(after),
+	)
+
+	return err
+}
+
+func jsonParam(b []byte) any {
+	if b == nil {
+		return nil
+	}
+
+	return string(b)
+}
+
+func (r *repo) FindEntries(ctx context.Context, filter models.AuditFilter) (models.AuditEntries, error) {
+	args := []any{}
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := []string{"true"}
+
+	if filter.Action != "" {
+		where = append(where, "action = "+arg(filter.Action))
+	}
+
+	if filter.ActorID > 0 {
+		where = append(where, "actor_id = "+arg(filter.ActorID))
+	}
+
+	if filter.TargetType != "" {
+		where = append(where, "target_type = "+arg(filter.TargetType))
+	}
+
+	if filter.TargetID != "" {
+		where = append(where, "target_id = "+arg(filter.TargetID))
+	}
+
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= "+arg(filter.Since))
+	}
+
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < "+arg(filter.Until))
+	}
+
+	if filter.After != nil {
+		where = append(where, "audit_id < "+arg(filter.After.AuditID))
+	}
+
+	q := fmt.Sprintf(`
+  SELECT audit_id, action, actor_id, target_type, target_id, ip, user_agent, 
+  before, after, created_at
+  FROM audit_log
+  WHERE %s
+  ORDER BY audit_id DESC
+  LIMIT %s;
+  `, strings.Join(where, " AND "), arg(filter.Limit+1))
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return make(models.AuditEntries, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	entries := make(models.AuditEntries, 0)
+
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after []byte
+
+		err := rows.Scan(
+			&e.AuditID,
+			&e.Action,
+			&e.ActorID,
+			&e.TargetType,
+			&e.TargetID,
+			&e.IP,
+			&e.UserAgent,
+			&before,
+			&after,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return make(models.AuditEntries, 0), err
+		}
+
+		if before != nil {
+			e.Before = before
+		}
+
+		if after != nil {
+			e.After = after
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.AuditEntries, 0), err
+	}
+
+	return entries, nil
+}
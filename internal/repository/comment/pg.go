@@ -0,0 +1,216 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/utils"
+)
+
+type Repo interface {
+	CreateComment(context.Context, models.CommentIn) (models.Comment, error)
+	FindComments(context.Context, models.CommentFilter) (models.Comments, error)
+	FindCommentByID(context.Context, int, int) (models.Comment, error)
+	UpdateCommentByID(context.Context, string, int, int) (models.Comment, error)
+	DeleteCommentByID(context.Context, int) error
+}
+
+type repo struct {
+	db utils.DBTX
+}
+
+func NewRepo(db utils.DBTX) Repo {
+	return &repo{
+		db,
+	}
+}
+
+const replyCount = `(
+    SELECT count(*) FROM comments r WHERE r.parent_id = c.comment_id
+  )`
+
+func (r *repo) CreateComment(ctx context.Context, payload models.CommentIn) (models.Comment, error) {
+	q := `
+  INSERT INTO comments (post_id, parent_id, author_id, body)
+  SELECT $1::int, $2::int, $3::int, $4::text
+  WHERE $2::int IS NULL OR EXISTS (
+    SELECT 1 FROM comments 
+    WHERE comment_id = $2 AND post_id = $1 AND deleted_at IS NULL
+  )
+  RETURNING comment_id, post_id, parent_id, author_id, body, 
+  deleted_at IS NOT NULL, 0, version, created_at, updated_at;
+  `
+
+	row := r.db.QueryRowContext(
+		ctx,
+		q,
+		payload.PostID,
+		payload.ParentID,
+		payload.AuthorID,
+		payload.Body,
+	)
+
+	comment := models.Comment{}
+
+	err := scanComment(row, &comment)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Comment{}, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (r *repo) FindComments(ctx context.Context, filter models.CommentFilter) (models.Comments, error) {
+	args := []any{filter.PostID}
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	parent := "c.parent_id IS NULL"
+	if filter.ParentID > 0 {
+		parent = "c.parent_id = " + arg(filter.ParentID)
+	}
+
+	after := ""
+	if filter.After != nil {
+		after = "AND c.comment_id > " + arg(filter.After.CommentID)
+	}
+
+	q := fmt.Sprintf(`
+  SELECT c.comment_id, c.post_id, c.parent_id, c.author_id, c.body, 
+  c.deleted_at IS NOT NULL, %s, c.version, c.created_at, c.updated_at
+  FROM comments c
+  WHERE c.post_id = $1 AND %s %s
+  ORDER BY c.comment_id
+  LIMIT %s;
+  `, replyCount, parent, after, arg(filter.Limit+1))
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return make(models.Comments, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	comments := make(models.Comments, 0)
+
+	for rows.Next() {
+		var comment models.Comment
+		err := scanComment(rows, &comment)
+		if err != nil {
+			return make(models.Comments, 0), err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.Comments, 0), err
+	}
+
+	return comments, nil
+}
+
+func (r *repo) FindCommentByID(ctx context.Context, postID, commentID int) (models.Comment, error) {
+	q := `
+  SELECT c.comment_id, c.post_id, c.parent_id, c.author_id, c.body, 
+  c.deleted_at IS NOT NULL, ` + replyCount + `, c.version, c.created_at, c.updated_at
+  FROM comments c
+  WHERE c.post_id = $1 AND c.comment_id = $2;
+  `
+
+	row := r.db.QueryRowContext(ctx, q, postID, commentID)
+
+	comment := models.Comment{}
+
+	err := scanComment(row, &comment)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Comment{}, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (r *repo) UpdateCommentByID(
+	ctx context.Context,
+	body string,
+	commentID, version int,
+) (models.Comment, error) {
+	q := `
+  UPDATE comments c SET body = $1, updated_at = now(), version = version + 1
+  WHERE comment_id = $2 AND version = $3 AND deleted_at IS NULL
+  RETURNING c.comment_id, c.post_id, c.parent_id, c.author_id, c.body, 
+  c.deleted_at IS NOT NULL, ` + replyCount + `, c.version, c.created_at, c.updated_at;
+  `
+
+	row := r.db.QueryRowContext(ctx, q, body, commentID, version)
+
+	comment := models.Comment{}
+
+	err := scanComment(row, &comment)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Comment{}, repository.ErrUpdateConflict
+	}
+
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (r *repo) DeleteCommentByID(ctx context.Context, commentID int) error {
+	q := `
+  UPDATE comments SET body = '', deleted_at = now(), version = version + 1
+  WHERE comment_id = $1 AND deleted_at IS NULL;
+  `
+
+	result, err := r.db.ExecContext(ctx, q, commentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+func scanComment[R utils.Row](r R, c *models.Comment) error {
+	return r.Scan(
+		&c.CommentID,
+		&c.PostID,
+		&c.ParentID,
+		&c.AuthorID,
+		&c.Body,
+		&c.Deleted,
+		&c.ReplyCount,
+		&c.Version,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+}
@@ -9,6 +9,7 @@ var (
 	ErrDuplicateKey     = errors.New("duplicate key")
 	ErrResourceNotFound = errors.New("resource not found")
 	ErrUpdateConflict   = errors.New("update conflict")
+	ErrTokenReused      = errors.New("token reused")
 )
 
 func DuplicateKey(e error) bool {
@@ -0,0 +1,118 @@
+package identity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/utils"
+)
+
+type Repo interface {
+	FindUserIDByIdentity(context.Context, string, string) (int, error)
+	CreateIdentity(context.Context, models.Identity) error
+	CreateState(context.Context, models.OIDCState) error
+	ConsumeState(context.Context, []byte, string) (models.OIDCState, error)
+}
+
+type repo struct {
+	db utils.DBTX
+}
+
+func NewRepo(db utils.DBTX) Repo {
+	return &repo{
+		db,
+	}
+}
+
+func (r *repo) FindUserIDByIdentity(ctx context.Context, provider, subject string) (int, error) {
+	q := `SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2;`
+
+	var userID int
+
+	err := r.db.QueryRowContext(ctx, q, provider, subject).Scan(&userID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (r *repo) CreateIdentity(ctx context.Context, identity models.Identity) error {
+	q := `
+  INSERT INTO user_identities (provider, subject, user_id, email)
+  VALUES ($1, $2, $3, $4);
+  `
+
+	_, err := r.db.ExecContext(
+		ctx,
+		q,
+		identity.Provider,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+	)
+
+	if err != nil && repository.DuplicateKey(err) {
+		return repository.ErrDuplicateKey
+	}
+
+	return err
+}
+
+func (r *repo) CreateState(ctx context.Context, state models.OIDCState) error {
+	q := `
+  WITH expired AS (
+    DELETE FROM oidc_states WHERE expires_at < now()
+  )
+  INSERT INTO oidc_states (hash, provider, nonce, code_verifier, expires_at)
+  VALUES ($1, $2, $3, $4, $5);
+  `
+
+	_, err := r.db.ExecContext(
+		ctx,
+		q,
+		state.Hash,
+		state.Provider,
+		state.Nonce,
+		state.CodeVerifier,
+		state.ExpiresAt,
+	)
+
+	return err
+}
+
+func (r *repo) ConsumeState(ctx context.Context, hash []byte, provider string) (models.OIDCState, error) {
+	q := `
+  DELETE FROM oidc_states 
+  WHERE hash = $1 AND provider = $2 AND expires_at > now()
+  RETURNING hash, provider, nonce, code_verifier, expires_at;
+  `
+
+	var state models.OIDCState
+
+	err := r.db.QueryRowContext(ctx, q, hash, provider).Scan(
+		&state.Hash,
+		&state.Provider,
+		&state.Nonce,
+		&state.CodeVerifier,
+		&state.ExpiresAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OIDCState{}, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.OIDCState{}, err
+	}
+
+	return state, nil
+}
@@ -0,0 +1,139 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"nexablog/internal/utils"
+)
+
+type Repo interface {
+	FindRetryAfter(ctx context.Context, keys ...string) (time.Duration, error)
+	Reserve(ctx context.Context, key string, window time.Duration, penalties []float64) (int, time.Time, bool, error)
+	Release(ctx context.Context, key string, reservedAt time.Time) error
+	DeleteThrottle(ctx context.Context, key string) error
+	DeleteStaleThrottles(ctx context.Context, window time.Duration) (int64, error)
+}
+
+type repo struct {
+	db utils.DBTX
+}
+
+func NewRepo(db utils.DBTX) Repo {
+	return &repo{
+		db,
+	}
+}
+
+func (r *repo) FindRetryAfter(ctx context.Context, keys ...string) (time.Duration, error) {
+	q := `
+  SELECT COALESCE(EXTRACT(EPOCH FROM MAX(blocked_until) - now()), 0)::float8
+  FROM login_throttles
+  WHERE key = ANY($1) AND blocked_until > now();
+  `
+
+	var seconds float64
+
+	err := r.db.QueryRowContext(ctx, q, pq.Array(keys)).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Reserve counts an attempt against key as failed before it is checked, and
+// blocks key for penalties[n-1] seconds, where n is the number of failures so
+// far, or for the last penalty when there are more failures than penalties.
+// The count starts over once window has passed since the previous failure.
+// It returns the number of failures and the time of the reservation, or false
+// without counting the attempt when key is already blocked.
+func (r *repo) Reserve(
+	ctx context.Context,
+	key string,
+	window time.Duration,
+	penalties []float64,
+) (int, time.Time, bool, error) {
+	q := `
+  INSERT INTO login_throttles AS t (key, failures, blocked_until)
+  VALUES ($1, 1, now() + ($3::float8[])[1] * INTERVAL '1 second')
+  ON CONFLICT (key) DO UPDATE
+  SET (failures, blocked_until, last_failed_at) = (
+    SELECT
+      n,
+      now() + ($3::float8[])[LEAST(n, cardinality($3::float8[]))] * INTERVAL '1 second',
+      now()
+    FROM (
+      SELECT CASE
+        WHEN t.last_failed_at < now() - $2::float8 * INTERVAL '1 second' THEN 1
+        ELSE t.failures + 1
+      END AS n
+    ) counted
+  )
+  WHERE t.blocked_until <= now()
+  RETURNING failures, last_failed_at;
+  `
+
+	var (
+		failures   int
+		reservedAt time.Time
+	)
+
+	err := r.db.QueryRowContext(ctx, q, key, window.Seconds(), pq.Array(penalties)).Scan(
+		&failures,
+		&reservedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, false, nil
+	}
+
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+
+	return failures, reservedAt, true, nil
+}
+
+// Release takes back an attempt reserved against key at reservedAt that did
+// not fail. The block it set is lifted unless another attempt was reserved
+// since, as the key was not blocked when it was reserved.
+func (r *repo) Release(ctx context.Context, key string, reservedAt time.Time) error {
+	q := `
+  UPDATE login_throttles 
+  SET failures = GREATEST(failures - 1, 0),
+      blocked_until = CASE WHEN last_failed_at = $2 THEN now() ELSE blocked_until END
+  WHERE key = $1;
+  `
+
+	_, err := r.db.ExecContext(ctx, q, key, reservedAt)
+
+	return err
+}
+
+func (r *repo) DeleteThrottle(ctx context.Context, key string) error {
+	q := `DELETE FROM login_throttles WHERE key = $1;`
+
+	_, err := r.db.ExecContext(ctx, q, key)
+
+	return err
+}
+
+func (r *repo) DeleteStaleThrottles(ctx context.Context, window time.Duration) (int64, error) {
+	q := `
+  DELETE FROM login_throttles
+  WHERE last_failed_at < now() - $1::float8 * INTERVAL '1 second'
+  AND blocked_until < now();
+  `
+
+	result, err := r.db.ExecContext(ctx, q, window.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
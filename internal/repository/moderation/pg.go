@@ -0,0 +1,69 @@
+package moderation
+
+import (
+	"context"
+
+	"nexablog/internal/models"
+	"nexablog/internal/utils"
+)
+
+type Repo interface {
+	FindModerations(context.Context, models.ModerationFilter) (models.Moderations, error)
+}
+
+type repo struct {
+	db utils.DBTX
+}
+
+func NewRepo(db utils.DBTX) Repo {
+	return &repo{
+		db,
+	}
+}
+
+func (r *repo) FindModerations(
+	ctx context.Context,
+	filter models.ModerationFilter,
+) (models.Moderations, error) {
+	q := `
+  SELECT moderation_id, post_id, author_id, moderator_id, action, reason, created_at
+  FROM post_moderations
+  WHERE ($1::int = 0 OR post_id = $1)
+  ORDER BY moderation_id DESC
+  LIMIT $2;
+  `
+
+	rows, err := r.db.QueryContext(ctx, q, filter.PostID, filter.Limit)
+	if err != nil {
+		return make(models.Moderations, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	moderations := make(models.Moderations, 0)
+
+	for rows.Next() {
+		var m models.Moderation
+		err := rows.Scan(
+			&m.ModerationID,
+			&m.PostID,
+			&m.AuthorID,
+			&m.ModeratorID,
+			&m.Action,
+			&m.Reason,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return make(models.Moderations, 0), err
+		}
+		moderations = append(moderations, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.Moderations, 0), err
+	}
+
+	return moderations, nil
+}
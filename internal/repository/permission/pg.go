@@ -6,12 +6,20 @@ import (
 	"github.com/lib/pq"
 
 	"nexablog/internal/models"
+	"nexablog/internal/repository"
 	"nexablog/internal/utils"
 )
 
 type Repo interface {
 	AddUserPermission(context.Context, int, ...string) error
+	RemoveUserPermission(context.Context, int, string) error
 	GetUserPermission(context.Context, int) (models.Permissions, error)
+	GetDirectUserPermission(context.Context, int) (models.Permissions, error)
+	FindAllPermissions(context.Context) (models.Permissions, error)
+	AddUserRole(context.Context, int, ...string) error
+	RemoveUserRole(context.Context, int, string) error
+	GetUserRoles(context.Context, int) ([]string, error)
+	FindAllRoles(context.Context) (models.Roles, error)
 }
 
 type repo struct {
@@ -27,7 +35,8 @@ func NewRepo(db utils.DBTX) Repo {
 func (r *repo) AddUserPermission(ctx context.Context, userID int, codes ...string) error {
 	q := `
   INSERT INTO users_permissions (user_id, permission_id) 
-  (SELECT $1, permission_id FROM permissions WHERE code = ANY($2));
+  (SELECT $1, permission_id FROM permissions WHERE code = ANY($2))
+  ON CONFLICT DO NOTHING;
   `
 
 	result, err := r.db.ExecContext(ctx, q, userID, pq.Array(codes))
@@ -42,15 +51,64 @@ func (r *repo) AddUserPermission(ctx context.Context, userID int, codes ...strin
 	return nil
 }
 
+func (r *repo) RemoveUserPermission(ctx context.Context, userID int, code string) error {
+	q := `
+  DELETE FROM users_permissions 
+  WHERE user_id = $1 AND permission_id = (SELECT permission_id FROM permissions WHERE code = $2);
+  `
+
+	result, err := r.db.ExecContext(ctx, q, userID, code)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
 func (r *repo) GetUserPermission(ctx context.Context, userID int) (models.Permissions, error) {
 	q := `
   SELECT p.permission_id, p.code FROM permissions p 
+  WHERE p.permission_id IN (
+    SELECT permission_id FROM users_permissions WHERE user_id = $1
+    UNION
+    SELECT rp.permission_id FROM roles_permissions rp
+    INNER JOIN users_roles ur USING(role_id)
+    WHERE ur.user_id = $1
+  )
+  ORDER BY p.code;
+  `
+
+	return r.findPermissions(ctx, q, userID)
+}
+
+func (r *repo) GetDirectUserPermission(ctx context.Context, userID int) (models.Permissions, error) {
+	q := `
+  SELECT p.permission_id, p.code FROM permissions p 
   INNER JOIN users_permissions up USING(permission_id)
-  INNER JOIN users u USING(user_id)
-  WHERE u.user_id = $1;
+  WHERE up.user_id = $1
+  ORDER BY p.code;
   `
 
-	rows, err := r.db.QueryContext(ctx, q, userID)
+	return r.findPermissions(ctx, q, userID)
+}
+
+func (r *repo) FindAllPermissions(ctx context.Context) (models.Permissions, error) {
+	q := `SELECT permission_id, code FROM permissions ORDER BY code;`
+
+	return r.findPermissions(ctx, q)
+}
+
+func (r *repo) findPermissions(ctx context.Context, q string, args ...any) (models.Permissions, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return make(models.Permissions, 0), err
 	}
@@ -76,3 +134,115 @@ func (r *repo) GetUserPermission(ctx context.Context, userID int) (models.Permis
 
 	return permissions, nil
 }
+
+func (r *repo) AddUserRole(ctx context.Context, userID int, roles ...string) error {
+	q := `
+  INSERT INTO users_roles (user_id, role_id) 
+  (SELECT $1, role_id FROM roles WHERE name = ANY($2))
+  ON CONFLICT DO NOTHING;
+  `
+
+	result, err := r.db.ExecContext(ctx, q, userID, pq.Array(roles))
+	if err != nil {
+		return err
+	}
+
+	if _, err := result.RowsAffected(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) RemoveUserRole(ctx context.Context, userID int, role string) error {
+	q := `
+  DELETE FROM users_roles 
+  WHERE user_id = $1 AND role_id = (SELECT role_id FROM roles WHERE name = $2);
+  `
+
+	result, err := r.db.ExecContext(ctx, q, userID, role)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+func (r *repo) GetUserRoles(ctx context.Context, userID int) ([]string, error) {
+	q := `
+  SELECT r.name FROM roles r 
+  INNER JOIN users_roles ur USING(role_id)
+  WHERE ur.user_id = $1
+  ORDER BY r.role_id;
+  `
+
+	rows, err := r.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return make([]string, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	roles := make([]string, 0)
+
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return make([]string, 0), err
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make([]string, 0), err
+	}
+
+	return roles, nil
+}
+
+func (r *repo) FindAllRoles(ctx context.Context) (models.Roles, error) {
+	q := `
+  SELECT r.name, COALESCE(array_agg(p.code ORDER BY p.code) FILTER (WHERE p.code IS NOT NULL), '{}')
+  FROM roles r 
+  LEFT JOIN roles_permissions rp USING(role_id)
+  LEFT JOIN permissions p USING(permission_id)
+  GROUP BY r.role_id, r.name
+  ORDER BY r.role_id;
+  `
+
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return make(models.Roles, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	roles := make(models.Roles, 0)
+
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.Name, pq.Array(&role.Permissions)); err != nil {
+			return make(models.Roles, 0), err
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.Roles, 0), err
+	}
+
+	return roles, nil
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 
 	"nexablog/internal/models"
 	"nexablog/internal/repository"
@@ -12,17 +16,28 @@ import (
 
 type Repo interface {
 	CreatePost(context.Context, models.PostIn) (models.Post, error)
-	FindAllPosts(context.Context) (models.Posts, error)
+	FindAllPosts(context.Context, models.PostFilter) (models.Posts, error)
 	FindPostByID(context.Context, int) (models.Post, error)
-	DeletePostByID(context.Context, int) error
-	UpdatePostByID(context.Context, models.PostIn, int, int) (models.Post, error)
+	DeletePostByID(context.Context, int, *models.ModerationIn) error
+	UpdatePostByID(context.Context, models.PostIn, int, int, *models.ModerationIn) (models.Post, error)
 	FindPostsByAuthor(context.Context, int) (models.Posts, error)
+	PublishScheduledPosts(context.Context) (int64, error)
+	SearchPosts(context.Context, models.PostSearch) (models.SearchResults, error)
+	FindPostBySlug(context.Context, string) (models.Post, error)
+	FindPostByOldSlug(context.Context, string) (models.Post, error)
+	FindTakenSlugs(context.Context, string, int) ([]string, error)
+	FindFeedState(context.Context, int) (models.FeedState, error)
 }
 
 type repo struct {
 	db utils.DBTX
 }
 
+const postTags = `COALESCE((
+    SELECT array_agg(t.name ORDER BY t.name) FROM posts_tags pt
+    INNER JOIN tags t USING(tag_id) WHERE pt.post_id = posts.post_id
+  ), '{}')`
+
 func NewRepo(db utils.DBTX) Repo {
 	return &repo{
 		db,
@@ -31,17 +46,56 @@ func NewRepo(db utils.DBTX) Repo {
 
 func (r *repo) CreatePost(ctx context.Context, payload models.PostIn) (models.Post, error) {
 	q := `
-  INSERT INTO posts 
-  (title, body, author_id)
-  VALUES ($1, $2, $3)
-  RETURNING post_id, title, body, author_id, version, created_at;
+  WITH created AS (
+    INSERT INTO posts 
+    (title, slug, body, status, published_at, author_id, search_vector)
+    VALUES (
+      $1, $8, $2, $3, $4, $5,
+      setweight(to_tsvector('english', $1::varchar), 'A') ||
+      setweight(to_tsvector('english', $2::text), 'B')
+    )
+    RETURNING post_id, title, slug, body, status, published_at, author_id, version, created_at
+  ), revision AS (
+    INSERT INTO post_revisions (post_id, version, title, body, editor_id)
+    SELECT post_id, version, title, body, NULLIF($6::int, 0) FROM created
+  ), input AS (
+    SELECT unnest($7::varchar[]) AS name
+  ), new_tags AS (
+    INSERT INTO tags (name) SELECT name FROM input
+    ON CONFLICT (name) DO NOTHING
+    RETURNING tag_id
+  ), post_tags AS (
+    INSERT INTO posts_tags (post_id, tag_id)
+    SELECT c.post_id, t.tag_id FROM created c, (
+      SELECT tag_id FROM new_tags
+      UNION SELECT tag_id FROM tags INNER JOIN input USING(name)
+    ) t
+  )
+  SELECT post_id, title, slug, body, status, published_at, author_id, version, created_at, $7
+  FROM created;
   `
 
-	row := r.db.QueryRowContext(ctx, q, payload.Title, payload.Body, payload.AuthorID)
+	row := r.db.QueryRowContext(
+		ctx,
+		q,
+		payload.Title,
+		payload.Body,
+		payload.Status,
+		payload.PublishedAt,
+		payload.AuthorID,
+		payload.EditorID,
+		pq.Array(payload.Tags),
+		payload.Slug,
+	)
 
 	post := models.Post{}
 
 	err := scanPost(row, &post)
+
+	if err != nil && repository.DuplicateKey(err) {
+		return models.Post{}, repository.ErrDuplicateKey
+	}
+
 	if err != nil {
 		return models.Post{}, err
 	}
@@ -49,13 +103,86 @@ func (r *repo) CreatePost(ctx context.Context, payload models.PostIn) (models.Po
 	return post, nil
 }
 
-func (r *repo) FindAllPosts(ctx context.Context) (models.Posts, error) {
-	q := `
-  SELECT post_id, title, body, author_id, version, created_at 
-  FROM posts;
-  `
+func (r *repo) FindAllPosts(ctx context.Context, filter models.PostFilter) (models.Posts, error) {
+	conds := make([]string, 0)
+	args := make([]any, 0)
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
-	rows, err := r.db.QueryContext(ctx, q)
+	if filter.AuthorID > 0 {
+		conds = append(conds, "author_id = "+arg(filter.AuthorID))
+	}
+
+	if filter.Status != "" {
+		conds = append(conds, "status = "+arg(filter.Status))
+	}
+
+	if filter.ViewerID > 0 {
+		conds = append(conds, fmt.Sprintf(
+			"(status = %s OR author_id = %s)",
+			arg(models.PostStatusPublished),
+			arg(filter.ViewerID),
+		))
+	} else {
+		conds = append(conds, "status = "+arg(models.PostStatusPublished))
+	}
+
+	if filter.Tag != "" {
+		conds = append(conds, fmt.Sprintf(`EXISTS (
+    SELECT 1 FROM posts_tags pt INNER JOIN tags t USING(tag_id)
+    WHERE pt.post_id = posts.post_id AND t.name = %s
+  )`, arg(filter.Tag)))
+	}
+
+	if !filter.CreatedAfter.IsZero() {
+		conds = append(conds, "created_at >= "+arg(filter.CreatedAfter))
+	}
+
+	if !filter.CreatedBefore.IsZero() {
+		conds = append(conds, "created_at < "+arg(filter.CreatedBefore))
+	}
+
+	cmp, dir := ">", "ASC"
+	if filter.Order == models.OrderDesc {
+		cmp, dir = "<", "DESC"
+	}
+
+	orderBy := fmt.Sprintf("post_id %s", dir)
+	if filter.Sort == models.PostSortCreatedAt {
+		orderBy = fmt.Sprintf("created_at %s, post_id %s", dir, dir)
+	}
+
+	if c := filter.After; c != nil {
+		switch filter.Sort {
+		case models.PostSortCreatedAt:
+			conds = append(conds, fmt.Sprintf(
+				"(created_at, post_id) %s (%s, %s)",
+				cmp,
+				arg(c.CreatedAt),
+				arg(c.PostID),
+			))
+		default:
+			conds = append(conds, fmt.Sprintf("post_id %s %s", cmp, arg(c.PostID)))
+		}
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	q := fmt.Sprintf(`
+  SELECT post_id, title, slug, body, status, published_at, author_id, version, created_at,
+  %s
+  FROM posts %s
+  ORDER BY %s
+  LIMIT %s;
+  `, postTags, where, orderBy, arg(filter.Limit+1))
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return make(models.Posts, 0), err
 	}
@@ -84,7 +211,8 @@ func (r *repo) FindAllPosts(ctx context.Context) (models.Posts, error) {
 
 func (r *repo) FindPostByID(ctx context.Context, postID int) (models.Post, error) {
 	q := `
-  SELECT post_id, title, body, author_id, version, created_at
+  SELECT post_id, title, slug, body, status, published_at, author_id, version, created_at,
+  ` + postTags + `
   FROM posts WHERE post_id = $1;
   `
 
@@ -105,20 +233,156 @@ func (r *repo) FindPostByID(ctx context.Context, postID int) (models.Post, error
 	return post, nil
 }
 
-func (r *repo) DeletePostByID(ctx context.Context, postID int) error {
-	q := `DELETE FROM posts WHERE post_id = $1;`
+func (r *repo) FindPostBySlug(ctx context.Context, slug string) (models.Post, error) {
+	q := `
+  SELECT post_id, title, slug, body, status, published_at, author_id, version, created_at,
+  ` + postTags + `
+  FROM posts WHERE slug = $1;
+  `
+
+	row := r.db.QueryRowContext(ctx, q, slug)
+
+	post := models.Post{}
+
+	err := scanPost(row, &post)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Post{}, repository.ErrResourceNotFound
+	}
 
-	result, err := r.db.ExecContext(ctx, q, postID)
 	if err != nil {
-		return err
+		return models.Post{}, err
+	}
+
+	return post, nil
+}
+
+func (r *repo) FindPostByOldSlug(ctx context.Context, slug string) (models.Post, error) {
+	q := `
+  SELECT post_id, title, slug, body, status, published_at, author_id, version, created_at,
+  ` + postTags + `
+  FROM posts WHERE post_id = (SELECT post_id FROM post_slugs WHERE slug = $1);
+  `
+
+	row := r.db.QueryRowContext(ctx, q, slug)
+
+	post := models.Post{}
+
+	err := scanPost(row, &post)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Post{}, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	return post, nil
+}
+
+func (r *repo) FindTakenSlugs(ctx context.Context, base string, postID int) ([]string, error) {
+	q := `
+  SELECT slug FROM posts
+  WHERE (slug = $1 OR slug LIKE $2) AND post_id <> $3
+  UNION
+  SELECT slug FROM post_slugs
+  WHERE (slug = $1 OR slug LIKE $2) AND post_id <> $3;
+  `
+
+	rows, err := r.db.QueryContext(ctx, q, base, base+"-%", postID)
+	if err != nil {
+		return []string{}, err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	slugs := make([]string, 0)
+
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return []string{}, err
+		}
+		slugs = append(slugs, slug)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if err := rows.Err(); err != nil {
+		return []string{}, err
+	}
+
+	return slugs, nil
+}
+
+func (r *repo) FindFeedState(ctx context.Context, authorID int) (models.FeedState, error) {
+	q := `
+  SELECT 
+  COALESCE(max(GREATEST(p.published_at, p.created_at, r.latest)), 'epoch'),
+  count(*),
+  COALESCE(max(p.post_id), 0)
+  FROM posts p
+  LEFT JOIN LATERAL (
+    SELECT max(created_at) AS latest FROM post_revisions WHERE post_id = p.post_id
+  ) r ON true
+  WHERE p.status = $1 AND ($2 = 0 OR p.author_id = $2);
+  `
+
+	var state models.FeedState
+
+	err := r.db.QueryRowContext(ctx, q, models.PostStatusPublished, authorID).Scan(
+		&state.LastModified,
+		&state.Count,
+		&state.LatestID,
+	)
 	if err != nil {
+		return models.FeedState{}, err
+	}
+
+	return state, nil
+}
+
+// moderationArgs returns the columns of the moderation record written along
+// with a post change, which are all NULL when the change is not moderated.
+func moderationArgs(moderation *models.ModerationIn) []any {
+	if moderation == nil {
+		return []any{nil, nil, nil, nil}
+	}
+
+	return []any{
+		moderation.AuthorID,
+		moderation.ModeratorID,
+		moderation.Action,
+		moderation.Reason,
+	}
+}
+
+func (r *repo) DeletePostByID(
+	ctx context.Context,
+	postID int,
+	moderation *models.ModerationIn,
+) error {
+	q := `
+  WITH deleted AS (
+    DELETE FROM posts WHERE post_id = $1 RETURNING post_id
+  ), moderated AS (
+    INSERT INTO post_moderations (post_id, author_id, moderator_id, action, reason)
+    SELECT post_id, $2::int, $3::int, $4::varchar, $5::text FROM deleted
+    WHERE $3::int IS NOT NULL
+  )
+  SELECT count(*) FROM deleted;
+  `
+
+	args := append([]any{postID}, moderationArgs(moderation)...)
+
+	var deleted int
+
+	if err := r.db.QueryRowContext(ctx, q, args...).Scan(&deleted); err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
+	if deleted == 0 {
 		return repository.ErrResourceNotFound
 	}
 
@@ -129,21 +393,69 @@ func (r *repo) UpdatePostByID(
 	ctx context.Context,
 	payload models.PostIn,
 	postID, version int,
+	moderation *models.ModerationIn,
 ) (models.Post, error) {
 	q := `
-  UPDATE posts SET title = $1, body = $2, version = version + 1
-  WHERE post_id = $3 AND version = $4
-  RETURNING post_id, title, body, author_id, version, created_at;
+  WITH previous AS (
+    SELECT post_id, slug FROM posts WHERE post_id = $5
+  ), updated AS (
+    UPDATE posts 
+    SET title = $1, slug = $9, body = $2, status = $3, published_at = $4, version = version + 1,
+    search_vector = 
+      setweight(to_tsvector('english', $1::varchar), 'A') ||
+      setweight(to_tsvector('english', $2::text), 'B')
+    WHERE post_id = $5 AND version = $6
+    RETURNING post_id, title, slug, body, status, published_at, author_id, version, created_at
+  ), revision AS (
+    INSERT INTO post_revisions (post_id, version, title, body, editor_id)
+    SELECT post_id, version, title, body, NULLIF($7::int, 0) FROM updated
+  ), input AS (
+    SELECT unnest($8::varchar[]) AS name
+  ), new_tags AS (
+    INSERT INTO tags (name) SELECT name FROM input
+    ON CONFLICT (name) DO NOTHING
+    RETURNING tag_id
+  ), wanted AS (
+    SELECT tag_id FROM new_tags
+    UNION SELECT tag_id FROM tags INNER JOIN input USING(name)
+  ), removed AS (
+    DELETE FROM posts_tags
+    WHERE post_id IN (SELECT post_id FROM updated)
+    AND tag_id NOT IN (SELECT tag_id FROM wanted)
+  ), added AS (
+    INSERT INTO posts_tags (post_id, tag_id)
+    SELECT u.post_id, w.tag_id FROM updated u, wanted w
+    ON CONFLICT DO NOTHING
+  ), reclaimed AS (
+    DELETE FROM post_slugs
+    WHERE slug = $9 AND post_id IN (SELECT post_id FROM updated)
+  ), redirected AS (
+    INSERT INTO post_slugs (slug, post_id)
+    SELECT p.slug, p.post_id FROM previous p, updated u
+    WHERE p.slug <> u.slug
+    ON CONFLICT DO NOTHING
+  ), moderated AS (
+    INSERT INTO post_moderations (post_id, author_id, moderator_id, action, reason)
+    SELECT post_id, $10::int, $11::int, $12::varchar, $13::text FROM updated
+    WHERE $11::int IS NOT NULL
+  )
+  SELECT post_id, title, slug, body, status, published_at, author_id, version, created_at, $8
+  FROM updated;
   `
 
-	row := r.db.QueryRowContext(
-		ctx,
-		q,
+	args := append([]any{
 		payload.Title,
 		payload.Body,
+		payload.Status,
+		payload.PublishedAt,
 		postID,
 		version,
-	)
+		payload.EditorID,
+		pq.Array(payload.Tags),
+		payload.Slug,
+	}, moderationArgs(moderation)...)
+
+	row := r.db.QueryRowContext(ctx, q, args...)
 
 	post := models.Post{}
 
@@ -153,6 +465,10 @@ func (r *repo) UpdatePostByID(
 		return models.Post{}, repository.ErrUpdateConflict
 	}
 
+	if err != nil && repository.DuplicateKey(err) {
+		return models.Post{}, repository.ErrDuplicateKey
+	}
+
 	if err != nil {
 		return models.Post{}, err
 	}
@@ -162,7 +478,8 @@ func (r *repo) UpdatePostByID(
 
 func (r *repo) FindPostsByAuthor(ctx context.Context, authorID int) (models.Posts, error) {
 	q := `
-  SELECT post_id, title, body, author_id, version, created_at
+  SELECT post_id, title, slug, body, status, published_at, author_id, version, created_at,
+  ` + postTags + `
   FROM posts WHERE author_id = $1;
   `
 
@@ -193,13 +510,119 @@ func (r *repo) FindPostsByAuthor(ctx context.Context, authorID int) (models.Post
 	return posts, nil
 }
 
+func (r *repo) PublishScheduledPosts(ctx context.Context) (int64, error) {
+	q := `
+  UPDATE posts SET status = $1
+  WHERE status = $2 AND published_at <= now();
+  `
+
+	result, err := r.db.ExecContext(
+		ctx,
+		q,
+		models.PostStatusPublished,
+		models.PostStatusScheduled,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *repo) SearchPosts(
+	ctx context.Context,
+	search models.PostSearch,
+) (models.SearchResults, error) {
+	args := []any{search.TSQuery(), models.PostStatusPublished, search.ViewerID}
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	after := ""
+	if c := search.After; c != nil {
+		after = fmt.Sprintf(
+			"WHERE (rank, post_id) < (%s::real, %s)",
+			arg(c.Rank),
+			arg(c.PostID),
+		)
+	}
+
+	q := fmt.Sprintf(`
+  WITH query AS (
+    SELECT to_tsquery('english', $1) AS q
+  ), matches AS (
+    SELECT p.*, ts_rank(p.search_vector, query.q) AS rank
+    FROM posts p, query
+    WHERE p.search_vector @@ query.q
+    AND (p.status = $2 OR p.author_id = $3)
+  )
+  SELECT post_id, title, slug, body, status, published_at, author_id, version, created_at,
+  %s,
+  rank,
+  ts_headline('english', title, query.q, 'HighlightAll=true'),
+  ts_headline('english', body, query.q, 'MaxFragments=2, MaxWords=30, MinWords=10')
+  FROM (
+    SELECT * FROM matches %s
+    ORDER BY rank DESC, post_id DESC
+    LIMIT %s
+  ) posts, query
+  ORDER BY rank DESC, post_id DESC;
+  `, postTags, after, arg(search.Limit+1))
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return make(models.SearchResults, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	results := make(models.SearchResults, 0)
+
+	for rows.Next() {
+		var result models.SearchResult
+		err := rows.Scan(
+			&result.PostID,
+			&result.Title,
+			&result.Slug,
+			&result.Body,
+			&result.Status,
+			&result.PublishedAt,
+			&result.AuthorID,
+			&result.Version,
+			&result.CreatedAt,
+			pq.Array(&result.Tags),
+			&result.Rank,
+			&result.TitleHighlight,
+			&result.Snippet,
+		)
+		if err != nil {
+			return make(models.SearchResults, 0), err
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.SearchResults, 0), err
+	}
+
+	return results, nil
+}
+
 func scanPost[R utils.Row](r R, p *models.Post) error {
 	return r.Scan(
 		&p.PostID,
 		&p.Title,
+		&p.Slug,
 		&p.Body,
+		&p.Status,
+		&p.PublishedAt,
 		&p.AuthorID,
 		&p.Version,
 		&p.CreatedAt,
+		pq.Array(&p.Tags),
 	)
 }
@@ -0,0 +1,94 @@
+package revision
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/utils"
+)
+
+type Repo interface {
+	FindRevisionsByPost(context.Context, int) (models.Revisions, error)
+	FindRevision(context.Context, int, int) (models.Revision, error)
+}
+
+type repo struct {
+	db utils.DBTX
+}
+
+func NewRepo(db utils.DBTX) Repo {
+	return &repo{
+		db,
+	}
+}
+
+func (r *repo) FindRevisionsByPost(ctx context.Context, postID int) (models.Revisions, error) {
+	q := `
+  SELECT post_id, version, title, body, editor_id, created_at
+  FROM post_revisions WHERE post_id = $1
+  ORDER BY version DESC;
+  `
+
+	rows, err := r.db.QueryContext(ctx, q, postID)
+	if err != nil {
+		return make(models.Revisions, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	revisions := make(models.Revisions, 0)
+
+	for rows.Next() {
+		var revision models.Revision
+		err := scanRevision(rows, &revision)
+		if err != nil {
+			return make(models.Revisions, 0), err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.Revisions, 0), err
+	}
+
+	return revisions, nil
+}
+
+func (r *repo) FindRevision(ctx context.Context, postID, version int) (models.Revision, error) {
+	q := `
+  SELECT post_id, version, title, body, editor_id, created_at
+  FROM post_revisions WHERE post_id = $1 AND version = $2;
+  `
+
+	row := r.db.QueryRowContext(ctx, q, postID, version)
+
+	revision := models.Revision{}
+
+	err := scanRevision(row, &revision)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Revision{}, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Revision{}, err
+	}
+
+	return revision, nil
+}
+
+func scanRevision[R utils.Row](r R, rev *models.Revision) error {
+	return r.Scan(
+		&rev.PostID,
+		&rev.Version,
+		&rev.Title,
+		&rev.Body,
+		&rev.EditorID,
+		&rev.CreatedAt,
+	)
+}
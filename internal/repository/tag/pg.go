@@ -0,0 +1,139 @@
+package tag
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/utils"
+)
+
+type Repo interface {
+	FindAllTags(context.Context) (models.Tags, error)
+	RenameTag(context.Context, string, string) (models.Tag, error)
+	MergeTags(context.Context, string, string) error
+}
+
+type repo struct {
+	db utils.DBTX
+}
+
+func NewRepo(db utils.DBTX) Repo {
+	return &repo{
+		db,
+	}
+}
+
+func (r *repo) FindAllTags(ctx context.Context) (models.Tags, error) {
+	q := `
+  SELECT t.tag_id, t.name, count(p.post_id)
+  FROM tags t
+  LEFT JOIN posts_tags pt USING(tag_id)
+  LEFT JOIN posts p ON p.post_id = pt.post_id AND p.status = $1
+  GROUP BY t.tag_id, t.name
+  ORDER BY t.name;
+  `
+
+	rows, err := r.db.QueryContext(ctx, q, models.PostStatusPublished)
+	if err != nil {
+		return make(models.Tags, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	tags := make(models.Tags, 0)
+
+	for rows.Next() {
+		var tag models.Tag
+		err := scanTag(rows, &tag)
+		if err != nil {
+			return make(models.Tags, 0), err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.Tags, 0), err
+	}
+
+	return tags, nil
+}
+
+func (r *repo) RenameTag(ctx context.Context, name, newName string) (models.Tag, error) {
+	q := `
+  WITH renamed AS (
+    UPDATE tags SET name = $2 WHERE name = $1
+    RETURNING tag_id, name
+  )
+  SELECT r.tag_id, r.name, count(p.post_id)
+  FROM renamed r
+  LEFT JOIN posts_tags pt USING(tag_id)
+  LEFT JOIN posts p ON p.post_id = pt.post_id AND p.status = $3
+  GROUP BY r.tag_id, r.name;
+  `
+
+	row := r.db.QueryRowContext(ctx, q, name, newName, models.PostStatusPublished)
+
+	tag := models.Tag{}
+
+	err := scanTag(row, &tag)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Tag{}, repository.ErrResourceNotFound
+	}
+
+	if err != nil && repository.DuplicateKey(err) {
+		return models.Tag{}, repository.ErrDuplicateKey
+	}
+
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	return tag, nil
+}
+
+func (r *repo) MergeTags(ctx context.Context, name, into string) error {
+	q := `
+  WITH src AS (
+    SELECT tag_id FROM tags WHERE name = $1
+  ), dst AS (
+    SELECT tag_id FROM tags WHERE name = $2
+  ), moved AS (
+    INSERT INTO posts_tags (post_id, tag_id)
+    SELECT pt.post_id, dst.tag_id FROM posts_tags pt, src, dst
+    WHERE pt.tag_id = src.tag_id
+    ON CONFLICT DO NOTHING
+  )
+  DELETE FROM tags
+  WHERE tag_id IN (SELECT tag_id FROM src) AND EXISTS (SELECT 1 FROM dst);
+  `
+
+	result, err := r.db.ExecContext(ctx, q, name, into)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+func scanTag[R utils.Row](r R, t *models.Tag) error {
+	return r.Scan(
+		&t.TagID,
+		&t.Name,
+		&t.PostCount,
+	)
+}
@@ -2,13 +2,29 @@ package token
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
 
 	"nexablog/internal/models"
+	"nexablog/internal/repository"
 	"nexablog/internal/utils"
 )
 
 type Repo interface {
 	CreateToken(context.Context, models.Token) error
+	ConsumeToken(context.Context, []byte, models.Scope) (int, error)
+	DeleteAllForUser(context.Context, int, ...models.Scope) error
+	DeleteFamilyByHash(context.Context, []byte) error
+	TouchToken(context.Context, []byte) error
+	FindSessionsByUser(context.Context, int, []byte) (models.Sessions, error)
+	DeleteSession(context.Context, int, string) error
+	RotateRefreshToken(context.Context, []byte) (int, string, error)
+	CreatePersonalToken(context.Context, []byte, int, models.PersonalTokenIn) (models.PersonalToken, error)
+	FindPersonalTokens(context.Context, int) (models.PersonalTokens, error)
+	DeletePersonalToken(context.Context, int, int) error
+	FindTokenPermissions(context.Context, []byte) ([]string, error)
 }
 
 type repo struct {
@@ -23,8 +39,8 @@ func NewRepo(db utils.DBTX) Repo {
 
 func (r *repo) CreateToken(ctx context.Context, token models.Token) error {
 	q := `
-  INSERT INTO tokens (hash, user_id, expires_at, scope)
-  VALUES ($1, $2, $3, $4)
+  INSERT INTO tokens (hash, user_id, expires_at, scope, family, user_agent, ip)
+  VALUES ($1, $2, $3, $4, $5, $6, $7)
   `
 
 	result, err := r.db.ExecContext(
@@ -34,6 +50,9 @@ func (r *repo) CreateToken(ctx context.Context, token models.Token) error {
 		token.UserID,
 		token.ExpiresAt,
 		token.Scope,
+		token.Family,
+		token.UserAgent,
+		token.IP,
 	)
 	if err != nil {
 		return err
@@ -45,3 +64,334 @@ func (r *repo) CreateToken(ctx context.Context, token models.Token) error {
 
 	return nil
 }
+
+func (r *repo) ConsumeToken(ctx context.Context, hash []byte, scope models.Scope) (int, error) {
+	q := `
+  DELETE FROM tokens 
+  WHERE hash = $1 AND scope = $2 AND expires_at > now()
+  RETURNING user_id;
+  `
+
+	var userID int
+
+	err := r.db.QueryRowContext(ctx, q, hash, scope).Scan(&userID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (r *repo) DeleteAllForUser(ctx context.Context, userID int, scopes ...models.Scope) error {
+	q := `DELETE FROM tokens WHERE user_id = $1 AND scope = ANY($2);`
+
+	codes := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		codes = append(codes, string(scope))
+	}
+
+	_, err := r.db.ExecContext(ctx, q, userID, pq.Array(codes))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) DeleteFamilyByHash(ctx context.Context, hash []byte) error {
+	q := `
+  DELETE FROM tokens 
+  WHERE family = (SELECT family FROM tokens WHERE hash = $1);
+  `
+
+	result, err := r.db.ExecContext(ctx, q, hash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+func (r *repo) TouchToken(ctx context.Context, hash []byte) error {
+	q := `
+  UPDATE tokens SET last_used_at = now()
+  WHERE hash = $1 
+  AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
+  `
+
+	_, err := r.db.ExecContext(ctx, q, hash)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) FindSessionsByUser(
+	ctx context.Context,
+	userID int,
+	current []byte,
+) (models.Sessions, error) {
+	q := `
+  SELECT family,
+  (array_agg(user_agent ORDER BY token_id DESC))[1],
+  (array_agg(ip ORDER BY token_id DESC))[1],
+  bool_or(hash = $3),
+  min(created_at),
+  max(last_used_at),
+  max(expires_at)
+  FROM tokens
+  WHERE user_id = $1 AND scope = ANY($2) AND expires_at > now() AND used_at IS NULL
+  GROUP BY family
+  ORDER BY max(COALESCE(last_used_at, created_at)) DESC;
+  `
+
+	scopes := pq.Array([]string{
+		string(models.ScopeAuthentication),
+		string(models.ScopeRefresh),
+	})
+
+	rows, err := r.db.QueryContext(ctx, q, userID, scopes, current)
+	if err != nil {
+		return make(models.Sessions, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	sessions := make(models.Sessions, 0)
+
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(
+			&session.SessionID,
+			&session.UserAgent,
+			&session.IP,
+			&session.Current,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return make(models.Sessions, 0), err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.Sessions, 0), err
+	}
+
+	return sessions, nil
+}
+
+func (r *repo) DeleteSession(ctx context.Context, userID int, family string) error {
+	q := `DELETE FROM tokens WHERE family = $1 AND user_id = $2;`
+
+	result, err := r.db.ExecContext(ctx, q, family, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+func (r *repo) RotateRefreshToken(ctx context.Context, hash []byte) (int, string, error) {
+	q := `
+  UPDATE tokens SET used_at = now()
+  WHERE hash = $1 AND scope = $2 AND used_at IS NULL AND expires_at > now()
+  RETURNING user_id, family;
+  `
+
+	var (
+		userID int
+		family string
+	)
+
+	err := r.db.QueryRowContext(ctx, q, hash, models.ScopeRefresh).Scan(&userID, &family)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", r.revokeReusedFamily(ctx, hash)
+	}
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	return userID, family, nil
+}
+
+func (r *repo) revokeReusedFamily(ctx context.Context, hash []byte) error {
+	q := `
+  DELETE FROM tokens 
+  WHERE family = (
+    SELECT family FROM tokens 
+    WHERE hash = $1 AND scope = $2 AND used_at IS NOT NULL
+  );
+  `
+
+	result, err := r.db.ExecContext(ctx, q, hash, models.ScopeRefresh)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected > 0 {
+		return repository.ErrTokenReused
+	}
+
+	return repository.ErrResourceNotFound
+}
+
+func (r *repo) CreatePersonalToken(
+	ctx context.Context,
+	hash []byte,
+	userID int,
+	payload models.PersonalTokenIn,
+) (models.PersonalToken, error) {
+	q := `
+  INSERT INTO tokens (hash, user_id, expires_at, scope, family, name, permissions)
+  VALUES ($1, $2, $3, $4, encode($1, 'hex'), $5, $6)
+  RETURNING token_id, name, permissions, created_at, last_used_at, expires_at;
+  `
+
+	row := r.db.QueryRowContext(
+		ctx,
+		q,
+		hash,
+		userID,
+		payload.ExpiresAt,
+		models.ScopePersonal,
+		payload.Name,
+		pq.Array(payload.Permissions),
+	)
+
+	var token models.PersonalToken
+
+	err := scanPersonalToken(row, &token)
+
+	if err != nil && repository.DuplicateKey(err) {
+		return models.PersonalToken{}, repository.ErrDuplicateKey
+	}
+
+	if err != nil {
+		return models.PersonalToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *repo) FindPersonalTokens(ctx context.Context, userID int) (models.PersonalTokens, error) {
+	q := `
+  SELECT token_id, name, permissions, created_at, last_used_at, expires_at
+  FROM tokens
+  WHERE user_id = $1 AND scope = $2 AND (expires_at IS NULL OR expires_at > now())
+  ORDER BY token_id DESC;
+  `
+
+	rows, err := r.db.QueryContext(ctx, q, userID, models.ScopePersonal)
+	if err != nil {
+		return make(models.PersonalTokens, 0), err
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	tokens := make(models.PersonalTokens, 0)
+
+	for rows.Next() {
+		var token models.PersonalToken
+		if err := scanPersonalToken(rows, &token); err != nil {
+			return make(models.PersonalTokens, 0), err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return make(models.PersonalTokens, 0), err
+	}
+
+	return tokens, nil
+}
+
+func (r *repo) DeletePersonalToken(ctx context.Context, userID, tokenID int) error {
+	q := `DELETE FROM tokens WHERE token_id = $1 AND user_id = $2 AND scope = $3;`
+
+	result, err := r.db.ExecContext(ctx, q, tokenID, userID, models.ScopePersonal)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+func (r *repo) FindTokenPermissions(ctx context.Context, hash []byte) ([]string, error) {
+	q := `SELECT permissions FROM tokens WHERE hash = $1 AND scope = $2;`
+
+	permissions := make([]string, 0)
+
+	err := r.db.QueryRowContext(ctx, q, hash, models.ScopePersonal).Scan(pq.Array(&permissions))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if permissions == nil {
+		permissions = make([]string, 0)
+	}
+
+	return permissions, nil
+}
+
+func scanPersonalToken[R utils.Row](row R, t *models.PersonalToken) error {
+	return row.Scan(
+		&t.TokenID,
+		&t.Name,
+		pq.Array(&t.Permissions),
+		&t.CreatedAt,
+		&t.LastUsedAt,
+		&t.ExpiresAt,
+	)
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/lib/pq"
+
 	"nexablog/internal/models"
 	"nexablog/internal/repository"
 	"nexablog/internal/utils"
@@ -13,7 +15,17 @@ import (
 type Repo interface {
 	CreateUser(context.Context, models.UserIn) (models.User, error)
 	FindUserByEmail(context.Context, string) (models.User, error)
+	FindUserByID(context.Context, int) (models.User, error)
 	FindUserByToken(context.Context, string, models.Scope) (models.User, error)
+	ResetPassword(context.Context, []byte, string, ...models.Scope) (int, error)
+	ActivateUser(context.Context, int) (models.User, error)
+	ClaimUser(context.Context, int, string) (models.User, error)
+	SetTOTPSecret(context.Context, int, string) error
+	FindTOTPSecret(context.Context, int) (string, error)
+	EnableTOTP(context.Context, int, int64, [][]byte) error
+	DisableTOTP(context.Context, int) error
+	UseTOTPStep(context.Context, int, int64) error
+	UseRecoveryCode(context.Context, int, []byte) error
 }
 
 type repo struct {
@@ -30,7 +42,7 @@ func (r *repo) CreateUser(ctx context.Context, payload models.UserIn) (models.Us
 	q := `
   INSERT INTO users (username, email, password) 
   VALUES ($1, $2, $3)
-  RETURNING user_id, username, email, password, version, created_at;
+  RETURNING user_id, username, email, password, activated, totp_enabled, version, created_at;
   `
 
 	password, err := utils.GetPasswordHash(payload.Password)
@@ -63,7 +75,7 @@ func (r *repo) CreateUser(ctx context.Context, payload models.UserIn) (models.Us
 
 func (r *repo) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
 	q := `
-  SELECT user_id, username, email, password, version, created_at
+  SELECT user_id, username, email, password, activated, totp_enabled, version, created_at
   FROM users WHERE email = $1;
   `
 
@@ -84,12 +96,37 @@ func (r *repo) FindUserByEmail(ctx context.Context, email string) (models.User,
 	return user, nil
 }
 
+func (r *repo) FindUserByID(ctx context.Context, userID int) (models.User, error) {
+	q := `
+  SELECT user_id, username, email, password, activated, totp_enabled, version, created_at
+  FROM users WHERE user_id = $1;
+  `
+
+	row := r.db.QueryRowContext(ctx, q, userID)
+
+	var user models.User
+
+	err := scanUser(row, &user)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
 func scanUser(row *sql.Row, u *models.User) error {
 	return row.Scan(
 		&u.UserID,
 		&u.Username,
 		&u.Email,
 		&u.Password,
+		&u.Activated,
+		&u.TOTPEnabled,
 		&u.Version,
 		&u.CreatedAt,
 	)
@@ -102,9 +139,11 @@ func (r *repo) FindUserByToken(
 ) (models.User, error) {
 	q := `
   SELECT 
-  u.user_id, u.username, u.email, u.password, u.version, u.created_at
+  u.user_id, u.username, u.email, u.password, 
+  u.activated, u.totp_enabled, u.version, u.created_at
   FROM users u INNER JOIN tokens t USING(user_id)
-  WHERE t.hash = $1 AND t.scope = $2 AND t.expires_at > now();
+  WHERE t.hash = $1 AND t.scope = $2 
+  AND (t.expires_at IS NULL OR t.expires_at > now());
   `
 
 	hash := utils.HashRandString(token)
@@ -125,3 +164,264 @@ func (r *repo) FindUserByToken(
 
 	return user, nil
 }
+
+// ResetPassword consumes a password reset token and sets the password of its
+// user in one statement, revoking the user's sessions and other tokens.
+func (r *repo) ResetPassword(
+	ctx context.Context,
+	hash []byte,
+	plain string,
+	revoke ...models.Scope,
+) (int, error) {
+	q := `
+  WITH consumed AS (
+    DELETE FROM tokens
+    WHERE hash = $1 AND scope = $2 AND expires_at > now()
+    RETURNING user_id
+  ), revoked AS (
+    DELETE FROM tokens
+    WHERE user_id = (SELECT user_id FROM consumed) AND scope = ANY($4) AND hash <> $1
+  )
+  UPDATE users SET password = $3, version = version + 1
+  WHERE user_id = (SELECT user_id FROM consumed)
+  RETURNING user_id;
+  `
+
+	password, err := utils.GetPasswordHash(plain)
+	if err != nil {
+		return 0, err
+	}
+
+	codes := make([]string, 0, len(revoke))
+	for _, scope := range revoke {
+		codes = append(codes, string(scope))
+	}
+
+	var userID int
+
+	err = r.db.QueryRowContext(
+		ctx,
+		q,
+		hash,
+		models.ScopePasswordReset,
+		password,
+		pq.Array(codes),
+	).Scan(&userID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (r *repo) ActivateUser(ctx context.Context, userID int) (models.User, error) {
+	q := `
+  UPDATE users SET activated = true, version = version + 1
+  WHERE user_id = $1
+  RETURNING user_id, username, email, password, activated, totp_enabled, version, created_at;
+  `
+
+	row := r.db.QueryRowContext(ctx, q, userID)
+
+	var user models.User
+
+	err := scanUser(row, &user)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// ClaimUser activates an account that was never activated, replacing its
+// password and dropping its tokens and two-factor setup, which were all
+// created by someone who had not proven they own the email.
+func (r *repo) ClaimUser(ctx context.Context, userID int, plain string) (models.User, error) {
+	q := `
+  WITH revoked AS (
+    DELETE FROM tokens WHERE user_id = $1
+  ), cleared AS (
+    DELETE FROM recovery_codes WHERE user_id = $1
+  )
+  UPDATE users SET 
+  activated = true, password = $2, 
+  totp_enabled = false, totp_secret = NULL, totp_last_step = NULL, version = version + 1
+  WHERE user_id = $1 AND NOT activated
+  RETURNING user_id, username, email, password, activated, totp_enabled, version, created_at;
+  `
+
+	password, err := utils.GetPasswordHash(plain)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	row := r.db.QueryRowContext(ctx, q, userID, password)
+
+	var user models.User
+
+	err = scanUser(row, &user)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, repository.ErrUpdateConflict
+	}
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *repo) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	q := `
+  UPDATE users SET totp_secret = $1, totp_last_step = NULL, version = version + 1
+  WHERE user_id = $2 AND NOT totp_enabled;
+  `
+
+	result, err := r.db.ExecContext(ctx, q, secret, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrUpdateConflict
+	}
+
+	return nil
+}
+
+func (r *repo) FindTOTPSecret(ctx context.Context, userID int) (string, error) {
+	q := `SELECT totp_secret FROM users WHERE user_id = $1 AND totp_secret IS NOT NULL;`
+
+	var secret string
+
+	err := r.db.QueryRowContext(ctx, q, userID).Scan(&secret)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", repository.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+func (r *repo) EnableTOTP(ctx context.Context, userID int, step int64, codes [][]byte) error {
+	q := `
+  WITH enabled AS (
+    UPDATE users SET totp_enabled = true, totp_last_step = $2, version = version + 1
+    WHERE user_id = $1 AND NOT totp_enabled AND totp_secret IS NOT NULL
+    RETURNING user_id
+  ), cleared AS (
+    DELETE FROM recovery_codes WHERE user_id IN (SELECT user_id FROM enabled)
+  )
+  INSERT INTO recovery_codes (user_id, hash)
+  SELECT enabled.user_id, code FROM enabled, unnest($3::bytea[]) AS code;
+  `
+
+	result, err := r.db.ExecContext(ctx, q, userID, step, pq.Array(codes))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrUpdateConflict
+	}
+
+	return nil
+}
+
+func (r *repo) DisableTOTP(ctx context.Context, userID int) error {
+	q := `
+  WITH cleared AS (
+    DELETE FROM recovery_codes WHERE user_id = $1
+  )
+  UPDATE users SET 
+  totp_enabled = false, totp_secret = NULL, totp_last_step = NULL, version = version + 1
+  WHERE user_id = $1;
+  `
+
+	result, err := r.db.ExecContext(ctx, q, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+func (r *repo) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	q := `
+  UPDATE users SET totp_last_step = $2
+  WHERE user_id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2);
+  `
+
+	result, err := r.db.ExecContext(ctx, q, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrTokenReused
+	}
+
+	return nil
+}
+
+func (r *repo) UseRecoveryCode(ctx context.Context, userID int, hash []byte) error {
+	q := `
+  UPDATE recovery_codes SET used_at = now()
+  WHERE user_id = $1 AND hash = $2 AND used_at IS NULL;
+  `
+
+	result, err := r.db.ExecContext(ctx, q, userID, hash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository/audit"
+	"nexablog/internal/telemetry"
+)
+
+type Service interface {
+	Record(context.Context, models.AuditIn) error
+	FindEntries(context.Context, models.AuditFilter) (models.AuditPage, error)
+}
+
+type service struct {
+	timeout time.Duration
+	store   audit.Repo
+}
+
+func NewService(store audit.Repo) Service {
+	return &service{
+		3 * time.Second,
+		store,
+	}
+}
+
+func (s *service) Record(ctx context.Context, entry models.AuditIn) error {
+	ctx, span := telemetry.Start(ctx, "audit.Record")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	before, err := snapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := snapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	return s.store.CreateEntry(ctx, entry, before, after)
+}
+
+func (s *service) FindEntries(
+	ctx context.Context,
+	filter models.AuditFilter,
+) (models.AuditPage, error) {
+	ctx, span := telemetry.Start(ctx, "audit.FindEntries")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	entries, err := s.store.FindEntries(ctx, filter)
+	if err != nil {
+		return models.AuditPage{}, err
+	}
+
+	hasMore := len(entries) > filter.Limit
+	if hasMore {
+		entries = entries[:filter.Limit]
+	}
+
+	page := models.AuditPage{
+		Entries: entries,
+		Metadata: models.Metadata{
+			Limit:   filter.Limit,
+			Count:   len(entries),
+			HasMore: hasMore,
+		},
+	}
+
+	if hasMore {
+		page.NextCursor = models.EncodeCursor(models.AuditCursor{
+			AuditID: entries[len(entries)-1].AuditID,
+		})
+	}
+
+	return page, nil
+}
+
+func snapshot(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
@@ -0,0 +1,155 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/repository/comment"
+	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
+)
+
+type Service interface {
+	CreateComment(context.Context, models.CommentIn) (models.Comment, error)
+	FindComments(context.Context, models.CommentFilter) (models.CommentPage, error)
+	FindCommentByID(context.Context, int, int) (models.Comment, error)
+	UpdateCommentByID(context.Context, string, int, int) (models.Comment, error)
+	DeleteCommentByID(context.Context, int) error
+}
+
+type service struct {
+	timeout time.Duration
+	store   comment.Repo
+}
+
+func NewService(store comment.Repo) Service {
+	return &service{
+		3 * time.Second,
+		store,
+	}
+}
+
+func (s *service) CreateComment(ctx context.Context, payload models.CommentIn) (models.Comment, error) {
+	ctx, span := telemetry.Start(ctx, "comment.CreateComment")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	comment, err := s.store.CreateComment(ctx, payload)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.Comment{}, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (s *service) FindComments(
+	ctx context.Context,
+	filter models.CommentFilter,
+) (models.CommentPage, error) {
+	ctx, span := telemetry.Start(ctx, "comment.FindComments")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	comments, err := s.store.FindComments(ctx, filter)
+	if err != nil {
+		return models.CommentPage{}, err
+	}
+
+	hasMore := len(comments) > filter.Limit
+	if hasMore {
+		comments = comments[:filter.Limit]
+	}
+
+	page := models.CommentPage{
+		Comments: comments,
+		Metadata: models.Metadata{
+			Limit:   filter.Limit,
+			Count:   len(comments),
+			HasMore: hasMore,
+		},
+	}
+
+	if hasMore {
+		page.NextCursor = models.EncodeCursor(models.CommentCursor{
+			CommentID: comments[len(comments)-1].CommentID,
+		})
+	}
+
+	return page, nil
+}
+
+func (s *service) FindCommentByID(ctx context.Context, postID, commentID int) (models.Comment, error) {
+	ctx, span := telemetry.Start(ctx, "comment.FindCommentByID")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	comment, err := s.store.FindCommentByID(ctx, postID, commentID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.Comment{}, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (s *service) UpdateCommentByID(
+	ctx context.Context,
+	body string,
+	commentID, version int,
+) (models.Comment, error) {
+	ctx, span := telemetry.Start(ctx, "comment.UpdateCommentByID")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	comment, err := s.store.UpdateCommentByID(ctx, body, commentID, version)
+
+	if errors.Is(err, repository.ErrUpdateConflict) {
+		return models.Comment{}, services.ErrUpdateConflict
+	}
+
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (s *service) DeleteCommentByID(ctx context.Context, commentID int) error {
+	ctx, span := telemetry.Start(ctx, "comment.DeleteCommentByID")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.DeleteCommentByID(ctx, commentID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
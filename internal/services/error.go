@@ -6,4 +6,6 @@ var (
 	ErrDuplicateKey     = errors.New("duplicate key")
 	ErrResourceNotFound = errors.New("resource not found")
 	ErrUpdateConflict   = errors.New("update conflict")
+	ErrTokenReused      = errors.New("token reused")
+	ErrInvalidCode      = errors.New("invalid code")
 )
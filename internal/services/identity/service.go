@@ -0,0 +1,150 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/repository/identity"
+	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
+	"nexablog/internal/utils"
+	"nexablog/pkg/oidc"
+)
+
+const stateTTL = 10 * time.Minute
+
+type Service interface {
+	StartLogin(context.Context, string) (string, error)
+	CompleteLogin(context.Context, string, string, string) (oidc.Claims, error)
+	FindUserIDByIdentity(context.Context, string, string) (int, error)
+	LinkIdentity(context.Context, models.Identity) error
+}
+
+type service struct {
+	timeout   time.Duration
+	store     identity.Repo
+	providers map[string]*oidc.Provider
+}
+
+func NewService(store identity.Repo, providers map[string]*oidc.Provider) Service {
+	return &service{
+		3 * time.Second,
+		store,
+		providers,
+	}
+}
+
+func (s *service) StartLogin(ctx context.Context, name string) (string, error) {
+	ctx, span := telemetry.Start(ctx, "identity.StartLogin")
+	defer span.End()
+
+	provider, ok := s.providers[name]
+	if !ok {
+		return "", services.ErrResourceNotFound
+	}
+
+	state, err := utils.GenRandString(16)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := utils.GenRandString(16)
+	if err != nil {
+		return "", err
+	}
+
+	verifier, challenge, err := oidc.NewVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	dbctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err = s.store.CreateState(dbctx, models.OIDCState{
+		Hash:         utils.HashRandString(state),
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(stateTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(ctx, state, nonce, challenge)
+}
+
+func (s *service) CompleteLogin(ctx context.Context, name, code, state string) (oidc.Claims, error) {
+	ctx, span := telemetry.Start(ctx, "identity.CompleteLogin")
+	defer span.End()
+
+	provider, ok := s.providers[name]
+	if !ok {
+		return oidc.Claims{}, services.ErrResourceNotFound
+	}
+
+	dbctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	saved, err := s.store.ConsumeState(dbctx, utils.HashRandString(state), name)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return oidc.Claims{}, services.ErrInvalidCode
+	}
+
+	if err != nil {
+		return oidc.Claims{}, err
+	}
+
+	claims, err := provider.Exchange(ctx, code, saved.CodeVerifier, saved.Nonce)
+
+	if errors.Is(err, oidc.ErrExchange) || errors.Is(err, oidc.ErrInvalidToken) {
+		return oidc.Claims{}, services.ErrInvalidCode
+	}
+
+	if err != nil {
+		return oidc.Claims{}, err
+	}
+
+	return claims, nil
+}
+
+func (s *service) FindUserIDByIdentity(ctx context.Context, provider, subject string) (int, error) {
+	ctx, span := telemetry.Start(ctx, "identity.FindUserIDByIdentity")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	userID, err := s.store.FindUserIDByIdentity(ctx, provider, subject)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return 0, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (s *service) LinkIdentity(ctx context.Context, payload models.Identity) error {
+	ctx, span := telemetry.Start(ctx, "identity.LinkIdentity")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.CreateIdentity(ctx, payload)
+
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return services.ErrDuplicateKey
+	}
+
+	return err
+}
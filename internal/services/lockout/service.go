@@ -0,0 +1,222 @@
+package lockout
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository/lockout"
+	"nexablog/internal/telemetry"
+)
+
+// Policy decides how failed logins are punished. Once an account or an IP has
+// failed BackoffAfter times, each further failure doubles the wait, starting
+// at BackoffBase and capped at BackoffMax. An account that fails LockAfter
+// times is locked for LockDuration. Failures are forgotten after Window
+// without one. A zero threshold disables the rule.
+type Policy struct {
+	AccountBackoffAfter int
+	IPBackoffAfter      int
+	LockAfter           int
+	BackoffBase         time.Duration
+	BackoffMax          time.Duration
+	LockDuration        time.Duration
+	Window              time.Duration
+}
+
+// penaltySteps is how many failures get their own penalty. Further failures
+// get the last one, by which point the backoff has long reached its cap.
+const penaltySteps = 64
+
+type Service interface {
+	Reserve(context.Context, models.LoginAttempt) (models.LoginReservation, error)
+	Release(context.Context, models.LoginReservation) error
+	Reset(ctx context.Context, email string) error
+	PurgeStale(context.Context) (int64, error)
+}
+
+type service struct {
+	timeout time.Duration
+	store   lockout.Repo
+	policy  Policy
+}
+
+func NewService(store lockout.Repo, policy Policy) Service {
+	return &service{
+		3 * time.Second,
+		store,
+		policy,
+	}
+}
+
+// Reserve counts the attempt as failed against its IP and account before its
+// credentials are checked, so that concurrent attempts each see the ones
+// before them. An attempt that succeeds must be given back with Release.
+func (s *service) Reserve(
+	ctx context.Context,
+	attempt models.LoginAttempt,
+) (models.LoginReservation, error) {
+	ctx, span := telemetry.Start(ctx, "lockout.Reserve")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	reservation := models.LoginReservation{Attempt: attempt}
+
+	if attempt.IP != "" {
+		_, reservedAt, ok, err := s.store.Reserve(
+			ctx,
+			ipKey(attempt.IP),
+			s.policy.Window,
+			s.ipPenalties(),
+		)
+		if err != nil {
+			return reservation, err
+		}
+
+		if !ok {
+			return s.refuse(ctx, reservation, ipKey(attempt.IP))
+		}
+
+		reservation.IPReservedAt = reservedAt
+	}
+
+	failures, reservedAt, ok, err := s.store.Reserve(
+		ctx,
+		accountKey(attempt.Email),
+		s.policy.Window,
+		s.accountPenalties(),
+	)
+	if err != nil {
+		return reservation, err
+	}
+
+	if !ok {
+		if attempt.IP != "" {
+			err := s.store.Release(ctx, ipKey(attempt.IP), reservation.IPReservedAt)
+			if err != nil {
+				return reservation, err
+			}
+		}
+
+		return s.refuse(ctx, reservation, accountKey(attempt.Email))
+	}
+
+	reservation.AccountReservedAt = reservedAt
+
+	if s.policy.LockAfter > 0 && failures == s.policy.LockAfter {
+		reservation.LockedUntil = reservedAt.Add(s.policy.LockDuration)
+	}
+
+	return reservation, nil
+}
+
+func (s *service) Release(ctx context.Context, reservation models.LoginReservation) error {
+	ctx, span := telemetry.Start(ctx, "lockout.Release")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	attempt := reservation.Attempt
+
+	if attempt.IP != "" {
+		if err := s.store.Release(ctx, ipKey(attempt.IP), reservation.IPReservedAt); err != nil {
+			return err
+		}
+	}
+
+	return s.store.Release(ctx, accountKey(attempt.Email), reservation.AccountReservedAt)
+}
+
+func (s *service) Reset(ctx context.Context, email string) error {
+	ctx, span := telemetry.Start(ctx, "lockout.Reset")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.store.DeleteThrottle(ctx, accountKey(email))
+}
+
+func (s *service) PurgeStale(ctx context.Context) (int64, error) {
+	ctx, span := telemetry.Start(ctx, "lockout.PurgeStale")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.store.DeleteStaleThrottles(ctx, s.policy.Window)
+}
+
+func (s *service) refuse(
+	ctx context.Context,
+	reservation models.LoginReservation,
+	key string,
+) (models.LoginReservation, error) {
+	retryAfter, err := s.store.FindRetryAfter(ctx, key)
+	if err != nil {
+		return reservation, err
+	}
+
+	reservation.Refused = true
+	reservation.RetryAfter = max(retryAfter, time.Second)
+
+	return reservation, nil
+}
+
+// accountPenalties lists in seconds how long an account is blocked after its
+// first, second and further failures.
+func (s *service) accountPenalties() []float64 {
+	penalties := make([]float64, penaltySteps)
+
+	for i := range penalties {
+		failures := i + 1
+
+		if s.policy.LockAfter > 0 && failures >= s.policy.LockAfter {
+			penalties[i] = s.policy.LockDuration.Seconds()
+		} else {
+			penalties[i] = s.backoff(failures, s.policy.AccountBackoffAfter).Seconds()
+		}
+	}
+
+	return penalties
+}
+
+func (s *service) ipPenalties() []float64 {
+	penalties := make([]float64, penaltySteps)
+
+	for i := range penalties {
+		penalties[i] = s.backoff(i+1, s.policy.IPBackoffAfter).Seconds()
+	}
+
+	return penalties
+}
+
+func (s *service) backoff(failures, after int) time.Duration {
+	if after <= 0 || failures < after {
+		return 0
+	}
+
+	d := s.policy.BackoffBase
+
+	for i := after; i < failures && d < s.policy.BackoffMax; i++ {
+		d *= 2
+	}
+
+	if d > s.policy.BackoffMax {
+		return s.policy.BackoffMax
+	}
+
+	return d
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,44 @@
+package moderation
+
+import (
+	"context"
+	"time"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository/moderation"
+	"nexablog/internal/telemetry"
+)
+
+type Service interface {
+	FindModerations(context.Context, models.ModerationFilter) (models.Moderations, error)
+}
+
+type service struct {
+	timeout time.Duration
+	store   moderation.Repo
+}
+
+func NewService(store moderation.Repo) Service {
+	return &service{
+		3 * time.Second,
+		store,
+	}
+}
+
+func (s *service) FindModerations(
+	ctx context.Context,
+	filter models.ModerationFilter,
+) (models.Moderations, error) {
+	ctx, span := telemetry.Start(ctx, "moderation.FindModerations")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	moderations, err := s.store.FindModerations(ctx, filter)
+	if err != nil {
+		return models.Moderations{}, err
+	}
+
+	return moderations, nil
+}
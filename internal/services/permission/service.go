@@ -2,13 +2,25 @@ package permission
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
 	"nexablog/internal/repository/permission"
+	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
 )
 
 type Service interface {
 	AddUserPermission(context.Context, int, ...string) error
+	RemoveUserPermission(context.Context, int, string) error
+	GetUserPermission(context.Context, int) (models.Permissions, error)
+	FindAllPermissions(context.Context) (models.Permissions, error)
+	AddUserRole(context.Context, int, ...string) error
+	RemoveUserRole(context.Context, int, string) error
+	FindAllRoles(context.Context) (models.Roles, error)
+	FindUserAccess(context.Context, int) (models.UserAccess, error)
 }
 
 type service struct {
@@ -24,6 +36,9 @@ func NewService(store permission.Repo) Service {
 }
 
 func (s *service) AddUserPermission(ctx context.Context, userID int, codes ...string) error {
+	ctx, span := telemetry.Start(ctx, "permission.AddUserPermission")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -33,3 +48,122 @@ func (s *service) AddUserPermission(ctx context.Context, userID int, codes ...st
 
 	return nil
 }
+
+func (s *service) RemoveUserPermission(ctx context.Context, userID int, code string) error {
+	ctx, span := telemetry.Start(ctx, "permission.RemoveUserPermission")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.RemoveUserPermission(ctx, userID, code)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	return err
+}
+
+func (s *service) GetUserPermission(ctx context.Context, userID int) (models.Permissions, error) {
+	ctx, span := telemetry.Start(ctx, "permission.GetUserPermission")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	permissions, err := s.store.GetUserPermission(ctx, userID)
+	if err != nil {
+		return models.Permissions{}, err
+	}
+
+	return permissions, nil
+}
+
+func (s *service) FindAllPermissions(ctx context.Context) (models.Permissions, error) {
+	ctx, span := telemetry.Start(ctx, "permission.FindAllPermissions")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	permissions, err := s.store.FindAllPermissions(ctx)
+	if err != nil {
+		return models.Permissions{}, err
+	}
+
+	return permissions, nil
+}
+
+func (s *service) AddUserRole(ctx context.Context, userID int, roles ...string) error {
+	ctx, span := telemetry.Start(ctx, "permission.AddUserRole")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.store.AddUserRole(ctx, userID, roles...)
+}
+
+func (s *service) RemoveUserRole(ctx context.Context, userID int, role string) error {
+	ctx, span := telemetry.Start(ctx, "permission.RemoveUserRole")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.RemoveUserRole(ctx, userID, role)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	return err
+}
+
+func (s *service) FindAllRoles(ctx context.Context) (models.Roles, error) {
+	ctx, span := telemetry.Start(ctx, "permission.FindAllRoles")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	roles, err := s.store.FindAllRoles(ctx)
+	if err != nil {
+		return models.Roles{}, err
+	}
+
+	return roles, nil
+}
+
+func (s *service) FindUserAccess(ctx context.Context, userID int) (models.UserAccess, error) {
+	ctx, span := telemetry.Start(ctx, "permission.FindUserAccess")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	roles, err := s.store.GetUserRoles(ctx, userID)
+	if err != nil {
+		return models.UserAccess{}, err
+	}
+
+	direct, err := s.store.GetDirectUserPermission(ctx, userID)
+	if err != nil {
+		return models.UserAccess{}, err
+	}
+
+	effective, err := s.store.GetUserPermission(ctx, userID)
+	if err != nil {
+		return models.UserAccess{}, err
+	}
+
+	access := models.UserAccess{
+		UserID:      userID,
+		Roles:       roles,
+		Permissions: direct.Codes(),
+		Effective:   effective.Codes(),
+	}
+
+	return access, nil
+}
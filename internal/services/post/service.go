@@ -3,21 +3,31 @@ package post
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"nexablog/internal/models"
 	"nexablog/internal/repository"
 	"nexablog/internal/repository/post"
 	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
+	"nexablog/pkg/slug"
 )
 
+const slugAttempts = 3
+
 type Service interface {
 	CreatePost(context.Context, models.PostIn) (models.Post, error)
-	FindAllPosts(context.Context) (models.Posts, error)
+	FindAllPosts(context.Context, models.PostFilter) (models.PostPage, error)
 	FindPostByID(context.Context, int) (models.Post, error)
-	DeletePostByID(context.Context, int) error
-	UpdatePostByID(context.Context, models.PostIn, int, int) (models.Post, error)
+	DeletePostByID(context.Context, int, *models.ModerationIn) error
+	UpdatePostByID(context.Context, models.PostIn, int, int, *models.ModerationIn) (models.Post, error)
 	FindPostsByAuthor(context.Context, int) (models.Posts, error)
+	PublishScheduledPosts(context.Context) (int64, error)
+	SearchPosts(context.Context, models.PostSearch) (models.SearchPage, error)
+	FindPostBySlug(context.Context, string) (models.Post, error)
+	FindPostByOldSlug(context.Context, string) (models.Post, error)
+	FindFeedState(context.Context, int) (models.FeedState, error)
 }
 
 type service struct {
@@ -33,30 +43,81 @@ func NewService(store post.Repo) Service {
 }
 
 func (s *service) CreatePost(ctx context.Context, payload models.PostIn) (models.Post, error) {
+	ctx, span := telemetry.Start(ctx, "post.CreatePost")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	post, err := s.store.CreatePost(ctx, payload)
-	if err != nil {
-		return models.Post{}, err
-	}
+	setPublishedAt(&payload)
 
-	return post, nil
+	for attempt := 1; ; attempt++ {
+		var err error
+
+		payload.Slug, err = s.uniqueSlug(ctx, payload.Title, 0)
+		if err != nil {
+			return models.Post{}, err
+		}
+
+		post, err := s.store.CreatePost(ctx, payload)
+
+		if errors.Is(err, repository.ErrDuplicateKey) && attempt < slugAttempts {
+			continue
+		}
+
+		if err != nil {
+			return models.Post{}, err
+		}
+
+		return post, nil
+	}
 }
 
-func (s *service) FindAllPosts(ctx context.Context) (models.Posts, error) {
+func (s *service) FindAllPosts(ctx context.Context, filter models.PostFilter) (models.PostPage, error) {
+	ctx, span := telemetry.Start(ctx, "post.FindAllPosts")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	posts, err := s.store.FindAllPosts(ctx)
+	posts, err := s.store.FindAllPosts(ctx, filter)
 	if err != nil {
-		return models.Posts{}, err
+		return models.PostPage{}, err
 	}
 
-	return posts, nil
+	hasMore := len(posts) > filter.Limit
+	if hasMore {
+		posts = posts[:filter.Limit]
+	}
+
+	page := models.PostPage{
+		Posts: posts,
+		Metadata: models.Metadata{
+			Limit:   filter.Limit,
+			Count:   len(posts),
+			Sort:    filter.Sort,
+			Order:   filter.Order,
+			HasMore: hasMore,
+		},
+	}
+
+	if hasMore {
+		last := posts[len(posts)-1]
+		page.NextCursor = models.EncodeCursor(models.PostCursor{
+			Sort:      filter.Sort,
+			Order:     filter.Order,
+			PostID:    last.PostID,
+			CreatedAt: last.CreatedAt,
+		})
+	}
+
+	return page, nil
 }
 
 func (s *service) FindPostByID(ctx context.Context, postID int) (models.Post, error) {
+	ctx, span := telemetry.Start(ctx, "post.FindPostByID")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -73,11 +134,20 @@ func (s *service) FindPostByID(ctx context.Context, postID int) (models.Post, er
 	return post, nil
 }
 
-func (s *service) DeletePostByID(ctx context.Context, postID int) error {
+// DeletePostByID deletes a post, recording moderation in the same statement
+// when it is not nil.
+func (s *service) DeletePostByID(
+	ctx context.Context,
+	postID int,
+	moderation *models.ModerationIn,
+) error {
+	ctx, span := telemetry.Start(ctx, "post.DeletePostByID")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	err := s.store.DeletePostByID(ctx, postID)
+	err := s.store.DeletePostByID(ctx, postID, moderation)
 
 	if errors.Is(err, repository.ErrResourceNotFound) {
 		return services.ErrResourceNotFound
@@ -90,28 +160,64 @@ func (s *service) DeletePostByID(ctx context.Context, postID int) error {
 	return nil
 }
 
+// UpdatePostByID updates a post, recording moderation in the same statement
+// when it is not nil.
 func (s *service) UpdatePostByID(
 	ctx context.Context,
 	payload models.PostIn,
 	postID, version int,
+	moderation *models.ModerationIn,
 ) (models.Post, error) {
+	ctx, span := telemetry.Start(ctx, "post.UpdatePostByID")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	post, err := s.store.UpdatePostByID(ctx, payload, postID, version)
+	setPublishedAt(&payload)
+
+	current, err := s.store.FindPostByID(ctx, postID)
 
-	if errors.Is(err, repository.ErrUpdateConflict) {
-		return models.Post{}, services.ErrUpdateConflict
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.Post{}, services.ErrResourceNotFound
 	}
 
 	if err != nil {
 		return models.Post{}, err
 	}
 
-	return post, nil
+	for attempt := 1; ; attempt++ {
+		payload.Slug = current.Slug
+
+		if payload.Title != current.Title {
+			payload.Slug, err = s.uniqueSlug(ctx, payload.Title, postID)
+			if err != nil {
+				return models.Post{}, err
+			}
+		}
+
+		post, err := s.store.UpdatePostByID(ctx, payload, postID, version, moderation)
+
+		if errors.Is(err, repository.ErrDuplicateKey) && attempt < slugAttempts {
+			continue
+		}
+
+		if errors.Is(err, repository.ErrUpdateConflict) {
+			return models.Post{}, services.ErrUpdateConflict
+		}
+
+		if err != nil {
+			return models.Post{}, err
+		}
+
+		return post, nil
+	}
 }
 
 func (s *service) FindPostsByAuthor(ctx context.Context, authorID int) (models.Posts, error) {
+	ctx, span := telemetry.Start(ctx, "post.FindPostsByAuthor")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -122,3 +228,151 @@ func (s *service) FindPostsByAuthor(ctx context.Context, authorID int) (models.P
 
 	return posts, nil
 }
+
+func (s *service) PublishScheduledPosts(ctx context.Context) (int64, error) {
+	ctx, span := telemetry.Start(ctx, "post.PublishScheduledPosts")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.store.PublishScheduledPosts(ctx)
+}
+
+func (s *service) SearchPosts(ctx context.Context, search models.PostSearch) (models.SearchPage, error) {
+	ctx, span := telemetry.Start(ctx, "post.SearchPosts")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	results, err := s.store.SearchPosts(ctx, search)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+
+	hasMore := len(results) > search.Limit
+	if hasMore {
+		results = results[:search.Limit]
+	}
+
+	page := models.SearchPage{
+		Results: results,
+		Metadata: models.Metadata{
+			Limit:   search.Limit,
+			Count:   len(results),
+			HasMore: hasMore,
+		},
+	}
+
+	if hasMore {
+		last := results[len(results)-1]
+		page.NextCursor = models.EncodeCursor(models.SearchCursor{
+			Rank:   last.Rank,
+			PostID: last.PostID,
+		})
+	}
+
+	return page, nil
+}
+
+func (s *service) FindPostBySlug(ctx context.Context, slug string) (models.Post, error) {
+	ctx, span := telemetry.Start(ctx, "post.FindPostBySlug")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	post, err := s.store.FindPostBySlug(ctx, slug)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.Post{}, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	return post, nil
+}
+
+func (s *service) FindPostByOldSlug(ctx context.Context, slug string) (models.Post, error) {
+	ctx, span := telemetry.Start(ctx, "post.FindPostByOldSlug")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	post, err := s.store.FindPostByOldSlug(ctx, slug)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.Post{}, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	return post, nil
+}
+
+func (s *service) FindFeedState(ctx context.Context, authorID int) (models.FeedState, error) {
+	ctx, span := telemetry.Start(ctx, "post.FindFeedState")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	state, err := s.store.FindFeedState(ctx, authorID)
+	if err != nil {
+		return models.FeedState{}, err
+	}
+
+	return state, nil
+}
+
+// uniqueSlug numbers the slug of title until it is not used by another post.
+// Long bases are shortened to make room for the number, so the taken slugs
+// are looked up again for every shortened base.
+func (s *service) uniqueSlug(ctx context.Context, title string, postID int) (string, error) {
+	base := slug.Make(title)
+	if base == "" {
+		base = "post"
+	}
+
+	taken := make(map[string]bool)
+	looked := make(map[string]bool)
+
+	for n := 1; ; n++ {
+		candidate := slug.WithSuffix(base, n)
+
+		stem := candidate
+		if n > 1 {
+			stem = candidate[:strings.LastIndexByte(candidate, '-')]
+		}
+
+		if !looked[stem] {
+			slugs, err := s.store.FindTakenSlugs(ctx, stem, postID)
+			if err != nil {
+				return "", err
+			}
+
+			for _, s := range slugs {
+				taken[s] = true
+			}
+
+			looked[stem] = true
+		}
+
+		if !taken[candidate] {
+			return candidate, nil
+		}
+	}
+}
+
+func setPublishedAt(payload *models.PostIn) {
+	if payload.Status == models.PostStatusPublished && payload.PublishedAt == nil {
+		now := time.Now()
+		payload.PublishedAt = &now
+	}
+}
@@ -0,0 +1,92 @@
+package revision
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/repository/revision"
+	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
+	"nexablog/pkg/diff"
+)
+
+type Service interface {
+	FindRevisionsByPost(context.Context, int) (models.Revisions, error)
+	FindRevision(context.Context, int, int) (models.Revision, error)
+	DiffRevisions(context.Context, int, int, int) (models.RevisionDiff, error)
+}
+
+type service struct {
+	timeout time.Duration
+	store   revision.Repo
+}
+
+func NewService(store revision.Repo) Service {
+	return &service{
+		3 * time.Second,
+		store,
+	}
+}
+
+func (s *service) FindRevisionsByPost(ctx context.Context, postID int) (models.Revisions, error) {
+	ctx, span := telemetry.Start(ctx, "revision.FindRevisionsByPost")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	revisions, err := s.store.FindRevisionsByPost(ctx, postID)
+	if err != nil {
+		return models.Revisions{}, err
+	}
+
+	return revisions, nil
+}
+
+func (s *service) FindRevision(ctx context.Context, postID, version int) (models.Revision, error) {
+	ctx, span := telemetry.Start(ctx, "revision.FindRevision")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	revision, err := s.store.FindRevision(ctx, postID, version)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.Revision{}, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.Revision{}, err
+	}
+
+	return revision, nil
+}
+
+func (s *service) DiffRevisions(
+	ctx context.Context,
+	postID, from, to int,
+) (models.RevisionDiff, error) {
+	a, err := s.FindRevision(ctx, postID, from)
+	if err != nil {
+		return models.RevisionDiff{}, err
+	}
+
+	b, err := s.FindRevision(ctx, postID, to)
+	if err != nil {
+		return models.RevisionDiff{}, err
+	}
+
+	out := models.RevisionDiff{
+		PostID: postID,
+		From:   from,
+		To:     to,
+		Title:  diff.Lines(a.Title, b.Title),
+		Body:   diff.Lines(a.Body, b.Body),
+	}
+
+	return out, nil
+}
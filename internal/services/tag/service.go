@@ -0,0 +1,90 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/repository/tag"
+	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
+)
+
+type Service interface {
+	FindAllTags(context.Context) (models.Tags, error)
+	RenameTag(context.Context, string, string) (models.Tag, error)
+	MergeTags(context.Context, string, string) error
+}
+
+type service struct {
+	timeout time.Duration
+	store   tag.Repo
+}
+
+func NewService(store tag.Repo) Service {
+	return &service{
+		3 * time.Second,
+		store,
+	}
+}
+
+func (s *service) FindAllTags(ctx context.Context) (models.Tags, error) {
+	ctx, span := telemetry.Start(ctx, "tag.FindAllTags")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tags, err := s.store.FindAllTags(ctx)
+	if err != nil {
+		return models.Tags{}, err
+	}
+
+	return tags, nil
+}
+
+func (s *service) RenameTag(ctx context.Context, name, newName string) (models.Tag, error) {
+	ctx, span := telemetry.Start(ctx, "tag.RenameTag")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tag, err := s.store.RenameTag(ctx, name, newName)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.Tag{}, services.ErrResourceNotFound
+	}
+
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return models.Tag{}, services.ErrDuplicateKey
+	}
+
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	return tag, nil
+}
+
+func (s *service) MergeTags(ctx context.Context, name, into string) error {
+	ctx, span := telemetry.Start(ctx, "tag.MergeTags")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.MergeTags(ctx, name, into)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
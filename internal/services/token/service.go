@@ -2,15 +2,29 @@ package token
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"nexablog/internal/models"
+	"nexablog/internal/repository"
 	"nexablog/internal/repository/token"
+	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
 	"nexablog/internal/utils"
 )
 
 type Service interface {
 	AddToken(context.Context, models.TokenIn) (models.TokenOut, error)
+	ConsumeToken(context.Context, string, models.Scope) (int, error)
+	DeleteAllForUser(context.Context, int, ...models.Scope) error
+	DeleteFamilyByHash(context.Context, []byte) error
+	TouchToken(context.Context, []byte) error
+	FindSessionsByUser(context.Context, int, []byte) (models.Sessions, error)
+	DeleteSession(context.Context, int, string) error
+	RotateRefreshToken(context.Context, string) (int, string, error)
+	AddPersonalToken(context.Context, int, models.PersonalTokenIn) (models.PersonalTokenOut, error)
+	FindPersonalTokens(context.Context, int) (models.PersonalTokens, error)
+	DeletePersonalToken(context.Context, int, int) error
 }
 
 type service struct {
@@ -23,6 +37,9 @@ func NewService(store token.Repo) Service {
 }
 
 func (s *service) AddToken(ctx context.Context, payload models.TokenIn) (models.TokenOut, error) {
+	ctx, span := telemetry.Start(ctx, "token.AddToken")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -31,17 +48,29 @@ func (s *service) AddToken(ctx context.Context, payload models.TokenIn) (models.
 		return models.TokenOut{}, err
 	}
 
+	if payload.Family == "" {
+		payload.Family, err = utils.GenRandString(16)
+		if err != nil {
+			return models.TokenOut{}, err
+		}
+	}
+
 	token := models.Token{
 		Hash:      utils.HashRandString(plain),
 		UserID:    payload.UserID,
 		ExpiresAt: payload.ExpiresAt,
 		Scope:     payload.Scope,
+		Family:    payload.Family,
+		UserAgent: payload.UserAgent,
+		IP:        payload.IP,
 	}
 
 	if err := s.store.CreateToken(ctx, token); err != nil {
 		return models.TokenOut{}, err
 	}
 
+	telemetry.TokensIssued.WithLabelValues(string(payload.Scope)).Inc()
+
 	out := models.TokenOut{
 		Plain:     plain,
 		ExpiresAt: payload.ExpiresAt,
@@ -49,3 +78,196 @@ func (s *service) AddToken(ctx context.Context, payload models.TokenIn) (models.
 
 	return out, nil
 }
+
+func (s *service) ConsumeToken(ctx context.Context, plain string, scope models.Scope) (int, error) {
+	ctx, span := telemetry.Start(ctx, "token.ConsumeToken")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	userID, err := s.store.ConsumeToken(ctx, utils.HashRandString(plain), scope)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return 0, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (s *service) DeleteAllForUser(ctx context.Context, userID int, scopes ...models.Scope) error {
+	ctx, span := telemetry.Start(ctx, "token.DeleteAllForUser")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.store.DeleteAllForUser(ctx, userID, scopes...)
+}
+
+func (s *service) DeleteFamilyByHash(ctx context.Context, hash []byte) error {
+	ctx, span := telemetry.Start(ctx, "token.DeleteFamilyByHash")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.DeleteFamilyByHash(ctx, hash)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) TouchToken(ctx context.Context, hash []byte) error {
+	ctx, span := telemetry.Start(ctx, "token.TouchToken")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.store.TouchToken(ctx, hash)
+}
+
+func (s *service) FindSessionsByUser(
+	ctx context.Context,
+	userID int,
+	current []byte,
+) (models.Sessions, error) {
+	ctx, span := telemetry.Start(ctx, "token.FindSessionsByUser")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	sessions, err := s.store.FindSessionsByUser(ctx, userID, current)
+	if err != nil {
+		return models.Sessions{}, err
+	}
+
+	return sessions, nil
+}
+
+func (s *service) DeleteSession(ctx context.Context, userID int, sessionID string) error {
+	ctx, span := telemetry.Start(ctx, "token.DeleteSession")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.DeleteSession(ctx, userID, sessionID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) RotateRefreshToken(ctx context.Context, plain string) (int, string, error) {
+	ctx, span := telemetry.Start(ctx, "token.RotateRefreshToken")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	userID, family, err := s.store.RotateRefreshToken(ctx, utils.HashRandString(plain))
+
+	switch {
+	case errors.Is(err, repository.ErrResourceNotFound):
+		return 0, "", services.ErrResourceNotFound
+	case errors.Is(err, repository.ErrTokenReused):
+		return 0, "", services.ErrTokenReused
+	case err != nil:
+		return 0, "", err
+	}
+
+	return userID, family, nil
+}
+
+func (s *service) AddPersonalToken(
+	ctx context.Context,
+	userID int,
+	payload models.PersonalTokenIn,
+) (models.PersonalTokenOut, error) {
+	ctx, span := telemetry.Start(ctx, "token.AddPersonalToken")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	plain, err := utils.GenRandString(16)
+	if err != nil {
+		return models.PersonalTokenOut{}, err
+	}
+
+	plain = models.PersonalTokenPrefix + plain
+
+	token, err := s.store.CreatePersonalToken(ctx, utils.HashRandString(plain), userID, payload)
+
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return models.PersonalTokenOut{}, services.ErrDuplicateKey
+	}
+
+	if err != nil {
+		return models.PersonalTokenOut{}, err
+	}
+
+	telemetry.TokensIssued.WithLabelValues(string(models.ScopePersonal)).Inc()
+
+	out := models.PersonalTokenOut{
+		PersonalToken: token,
+		Plain:         plain,
+	}
+
+	return out, nil
+}
+
+func (s *service) FindPersonalTokens(ctx context.Context, userID int) (models.PersonalTokens, error) {
+	ctx, span := telemetry.Start(ctx, "token.FindPersonalTokens")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tokens, err := s.store.FindPersonalTokens(ctx, userID)
+	if err != nil {
+		return models.PersonalTokens{}, err
+	}
+
+	return tokens, nil
+}
+
+func (s *service) DeletePersonalToken(ctx context.Context, userID, tokenID int) error {
+	ctx, span := telemetry.Start(ctx, "token.DeletePersonalToken")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.DeletePersonalToken(ctx, userID, tokenID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package totp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nexablog/internal/models"
+	"nexablog/internal/repository"
+	"nexablog/internal/repository/user"
+	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
+	"nexablog/internal/utils"
+	"nexablog/pkg/totp"
+)
+
+const recoveryCodeCount = 10
+
+type Service interface {
+	Enroll(context.Context, models.User) (models.TOTPEnrollment, error)
+	Confirm(context.Context, int, string) ([]string, error)
+	Disable(context.Context, int, string) error
+	Verify(context.Context, int, string) error
+}
+
+type service struct {
+	timeout time.Duration
+	store   user.Repo
+	issuer  string
+}
+
+func NewService(store user.Repo, issuer string) Service {
+	return &service{
+		3 * time.Second,
+		store,
+		issuer,
+	}
+}
+
+func (s *service) Enroll(ctx context.Context, u models.User) (models.TOTPEnrollment, error) {
+	ctx, span := telemetry.Start(ctx, "totp.Enroll")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+
+	err = s.store.SetTOTPSecret(ctx, u.UserID, secret)
+
+	if errors.Is(err, repository.ErrUpdateConflict) {
+		return models.TOTPEnrollment{}, services.ErrUpdateConflict
+	}
+
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+
+	enrollment := models.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, u.Email, secret),
+	}
+
+	return enrollment, nil
+}
+
+func (s *service) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	ctx, span := telemetry.Start(ctx, "totp.Confirm")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	secret, err := s.store.FindTOTPSecret(ctx, userID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, services.ErrInvalidCode
+	}
+
+	plain := make([]string, 0, recoveryCodeCount)
+	hashes := make([][]byte, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenRandString(5)
+		if err != nil {
+			return nil, err
+		}
+
+		plain = append(plain, code[:4]+"-"+code[4:])
+		hashes = append(hashes, utils.HashRandString(code))
+	}
+
+	err = s.store.EnableTOTP(ctx, userID, step, hashes)
+
+	if errors.Is(err, repository.ErrUpdateConflict) {
+		return nil, services.ErrUpdateConflict
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plain, nil
+}
+
+func (s *service) Disable(ctx context.Context, userID int, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	ctx, span := telemetry.Start(ctx, "totp.Disable")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.DisableTOTP(ctx, userID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	return err
+}
+
+func (s *service) Verify(ctx context.Context, userID int, code string) error {
+	ctx, span := telemetry.Start(ctx, "totp.Verify")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	secret, err := s.store.FindTOTPSecret(ctx, userID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrInvalidCode
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		err := s.store.UseTOTPStep(ctx, userID, step)
+
+		if errors.Is(err, repository.ErrTokenReused) {
+			return services.ErrInvalidCode
+		}
+
+		return err
+	}
+
+	hash := utils.HashRandString(models.NormalizeRecoveryCode(code))
+
+	err = s.store.UseRecoveryCode(ctx, userID, hash)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrInvalidCode
+	}
+
+	return err
+}
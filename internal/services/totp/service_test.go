@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"nexablog/internal/repository"
+	"nexablog/internal/repository/user"
+	"nexablog/internal/services"
+	"nexablog/pkg/totp"
+)
+
+// fakeRepo keeps the TOTP state of a single user, following the rules of the
+// Postgres repo.
+type fakeRepo struct {
+	user.Repo
+	secret   string
+	lastStep *int64
+}
+
+func (r *fakeRepo) FindTOTPSecret(context.Context, int) (string, error) {
+	return r.secret, nil
+}
+
+func (r *fakeRepo) UseTOTPStep(_ context.Context, _ int, step int64) error {
+	if r.lastStep != nil && *r.lastStep >= step {
+		return repository.ErrTokenReused
+	}
+
+	r.lastStep = &step
+
+	return nil
+}
+
+func (r *fakeRepo) UseRecoveryCode(context.Context, int, []byte) error {
+	return repository.ErrResourceNotFound
+}
+
+func TestVerifyRejectsReplayedCode(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	code, err := totp.Code(secret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous, err := totp.Code(secret, totp.Step(now)-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewService(&fakeRepo{secret: secret}, "nexablog")
+
+	if err := svc.Verify(context.Background(), 1, code); err != nil {
+		t.Fatalf("first Verify: %v", err)
+	}
+
+	if err := svc.Verify(context.Background(), 1, code); !errors.Is(err, services.ErrInvalidCode) {
+		t.Errorf("replayed Verify = %v, want %v", err, services.ErrInvalidCode)
+	}
+
+	if err := svc.Verify(context.Background(), 1, previous); !errors.Is(err, services.ErrInvalidCode) {
+		t.Errorf("earlier step Verify = %v, want %v", err, services.ErrInvalidCode)
+	}
+}
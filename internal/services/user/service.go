@@ -9,11 +9,18 @@ import (
 	"nexablog/internal/repository"
 	"nexablog/internal/repository/user"
 	"nexablog/internal/services"
+	"nexablog/internal/telemetry"
+	"nexablog/internal/utils"
 )
 
 type Service interface {
 	CreateUser(context.Context, models.UserIn) (models.User, error)
 	FindUserByEmail(context.Context, string) (models.User, error)
+	FindUserByID(context.Context, int) (models.User, error)
+	FindUserByToken(context.Context, string, models.Scope) (models.User, error)
+	ResetPassword(context.Context, string, string) error
+	ActivateUser(context.Context, int) (models.User, error)
+	ClaimUser(context.Context, int, string) (models.User, error)
 }
 
 type service struct {
@@ -29,6 +36,9 @@ func NewService(store user.Repo) Service {
 }
 
 func (s *service) CreateUser(ctx context.Context, payload models.UserIn) (models.User, error) {
+	ctx, span := telemetry.Start(ctx, "user.CreateUser")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -46,6 +56,9 @@ func (s *service) CreateUser(ctx context.Context, payload models.UserIn) (models
 }
 
 func (s *service) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
+	ctx, span := telemetry.Start(ctx, "user.FindUserByEmail")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -61,3 +74,118 @@ func (s *service) FindUserByEmail(ctx context.Context, email string) (models.Use
 
 	return user, nil
 }
+
+func (s *service) FindUserByID(ctx context.Context, userID int) (models.User, error) {
+	ctx, span := telemetry.Start(ctx, "user.FindUserByID")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.FindUserByID(ctx, userID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.User{}, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *service) FindUserByToken(
+	ctx context.Context,
+	token string,
+	scope models.Scope,
+) (models.User, error) {
+	ctx, span := telemetry.Start(ctx, "user.FindUserByToken")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.FindUserByToken(ctx, token, scope)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.User{}, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// ResetPassword sets the password of the user the reset token was issued
+// to, consuming the token and revoking the user's sessions and other tokens
+// in the same statement.
+func (s *service) ResetPassword(ctx context.Context, token, password string) error {
+	ctx, span := telemetry.Start(ctx, "user.ResetPassword")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.store.ResetPassword(
+		ctx,
+		utils.HashRandString(token),
+		password,
+		models.ScopeAuthentication,
+		models.ScopeRefresh,
+		models.ScopePersonal,
+		models.ScopePasswordReset,
+	)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) ActivateUser(ctx context.Context, userID int) (models.User, error) {
+	ctx, span := telemetry.Start(ctx, "user.ActivateUser")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.ActivateUser(ctx, userID)
+
+	if errors.Is(err, repository.ErrResourceNotFound) {
+		return models.User{}, services.ErrResourceNotFound
+	}
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *service) ClaimUser(ctx context.Context, userID int, password string) (models.User, error) {
+	ctx, span := telemetry.Start(ctx, "user.ClaimUser")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.ClaimUser(ctx, userID, password)
+
+	if errors.Is(err, repository.ErrUpdateConflict) {
+		return models.User{}, services.ErrUpdateConflict
+	}
+
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
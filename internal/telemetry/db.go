@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"nexablog/internal/utils"
+)
+
+type tracedDB struct {
+	db utils.DBTX
+}
+
+// TraceDB wraps db so that each query gets a span. The span of a query
+// covers its execution, not the iteration over the rows it returns.
+func TraceDB(db utils.DBTX) utils.DBTX {
+	return &tracedDB{db}
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	EndSpan(span, err)
+
+	return rows, err
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+
+	row := t.db.QueryRowContext(ctx, query, args...)
+
+	err := row.Err()
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+
+	EndSpan(span, err)
+
+	return row
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+
+	result, err := t.db.ExecContext(ctx, query, args...)
+	EndSpan(span, err)
+
+	return result, err
+}
+
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	statement := strings.Join(strings.Fields(query), " ")
+
+	operation, _, _ := strings.Cut(statement, " ")
+	operation = strings.ToUpper(operation)
+
+	return Start(
+		ctx,
+		"db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", statement),
+		),
+	)
+}
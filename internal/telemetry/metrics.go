@@ -0,0 +1,75 @@
+package telemetry
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nexablog_http_requests_total",
+			Help: "HTTP requests handled, by method, route pattern and status.",
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	HTTPDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "nexablog_http_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests, by method and route pattern.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route"},
+	)
+
+	HTTPInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nexablog_http_requests_in_flight",
+			Help: "HTTP requests being handled.",
+		},
+	)
+
+	TokensIssued = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nexablog_tokens_issued_total",
+			Help: "Tokens issued, by scope.",
+		},
+		[]string{"scope"},
+	)
+
+	LoginFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nexablog_login_failures_total",
+			Help: "Failed login attempts, by reason.",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		TokensIssued,
+		LoginFailures,
+	)
+}
+
+// ObserveDB exposes the connection pool statistics of db.
+func ObserveDB(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, "nexablog"))
+}
+
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
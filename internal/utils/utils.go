@@ -10,11 +10,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"nexablog/internal/models"
+	"nexablog/pkg/validator"
 )
 
 type DBTX interface {
@@ -90,6 +95,48 @@ func ReadJson(w http.ResponseWriter, r *http.Request, v any) error {
 	return nil
 }
 
+func ReadString(qs url.Values, key, def string) string {
+	s := qs.Get(key)
+
+	if s == "" {
+		return def
+	}
+
+	return s
+}
+
+func ReadInt(qs url.Values, key string, def int, v *validator.Validator) int {
+	s := qs.Get(key)
+
+	if s == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.Check(false, key, "must be an integer value")
+		return def
+	}
+
+	return i
+}
+
+func ReadTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.Check(false, key, "must be an RFC3339 timestamp")
+		return time.Time{}
+	}
+
+	return t
+}
+
 func GetPasswordHash(plain string) ([]byte, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
 	if err != nil {
@@ -130,7 +177,29 @@ func HashRandString(s string) []byte {
 	return h[:]
 }
 
+// RequestInfo is shared by every layer handling a request, so that what is
+// learnt deep in the middleware chain is visible to the logs around it.
+type RequestInfo struct {
+	ID     string
+	UserID int
+}
+
+func SetRequestInfo(r *http.Request, info *RequestInfo) *http.Request {
+	ctx := context.WithValue(r.Context(), "request_info", info)
+	return r.WithContext(ctx)
+}
+
+// GetRequestInfo returns nil outside of a request.
+func GetRequestInfo(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value("request_info").(*RequestInfo)
+	return info
+}
+
 func SetUser(r *http.Request, u *models.User) *http.Request {
+	if info := GetRequestInfo(r.Context()); info != nil && u != nil {
+		info.UserID = u.UserID
+	}
+
 	ctx := context.WithValue(r.Context(), "user", u)
 	return r.WithContext(ctx)
 }
@@ -144,3 +213,44 @@ func GetUser(r *http.Request) *models.User {
 
 	return user
 }
+
+func SetTokenHash(r *http.Request, hash []byte) *http.Request {
+	ctx := context.WithValue(r.Context(), "token_hash", hash)
+	return r.WithContext(ctx)
+}
+
+func GetTokenHash(r *http.Request) []byte {
+	hash, _ := r.Context().Value("token_hash").([]byte)
+	return hash
+}
+
+func SetTokenPermissions(r *http.Request, codes []string) *http.Request {
+	ctx := context.WithValue(r.Context(), "token_permissions", codes)
+	return r.WithContext(ctx)
+}
+
+// GetTokenPermissions returns nil when the request is not restricted by a
+// personal access token.
+func GetTokenPermissions(r *http.Request) []string {
+	codes, _ := r.Context().Value("token_permissions").([]string)
+	return codes
+}
+
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func UserAgent(r *http.Request) string {
+	ua := r.UserAgent()
+
+	if len(ua) > 512 {
+		return ua[:512]
+	}
+
+	return ua
+}
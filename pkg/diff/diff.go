@@ -0,0 +1,119 @@
+package diff
+
+import "strings"
+
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the LCS table built for the lines between the common
+// prefix and suffix, so that large bodies cannot exhaust memory.
+const maxCells = 1 << 20
+
+// Lines returns a line diff of a and b. When the changed region is too large
+// to compare line by line, it is reported as deleted and inserted as a whole.
+func Lines(a, b string) []Line {
+	return diff(split(a), split(b))
+}
+
+func split(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+func diff(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, max(len(a), len(b)))
+
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{OpEqual, text})
+	}
+
+	lines = append(lines, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{OpEqual, text})
+	}
+
+	return lines
+}
+
+func middle(a, b []string) []Line {
+	n, m := len(a), len(b)
+
+	if (n+1)*(m+1) > maxCells {
+		return replace(a, b)
+	}
+
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, max(n, m))
+
+	i, j := 0, 0
+
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{OpEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{OpDelete, a[i]})
+			i++
+		default:
+			lines = append(lines, Line{OpInsert, b[j]})
+			j++
+		}
+	}
+
+	return append(lines, replace(a[i:], b[j:])...)
+}
+
+func replace(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+
+	for _, text := range a {
+		lines = append(lines, Line{OpDelete, text})
+	}
+
+	for _, text := range b {
+		lines = append(lines, Line{OpInsert, text})
+	}
+
+	return lines
+}
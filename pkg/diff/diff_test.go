@@ -0,0 +1,50 @@
+package diff
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	got := Lines("a\nb\nc\nd", "a\nc\nx\nd")
+
+	want := []Line{
+		{OpEqual, "a"},
+		{OpDelete, "b"},
+		{OpEqual, "c"},
+		{OpInsert, "x"},
+		{OpEqual, "d"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %v, want %v", got, want)
+	}
+}
+
+func TestLinesReplacesLargeChanges(t *testing.T) {
+	a := make([]string, 2000)
+	b := make([]string, 2000)
+
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i)
+		b[i] = "b" + strconv.Itoa(i)
+	}
+
+	head, tail := "same\n", "\nend"
+
+	got := Lines(head+strings.Join(a, "\n")+tail, head+strings.Join(b, "\n")+tail)
+
+	if len(got) != len(a)+len(b)+2 {
+		t.Fatalf("len(Lines()) = %d, want %d", len(got), len(a)+len(b)+2)
+	}
+
+	if got[0] != (Line{OpEqual, "same"}) || got[len(got)-1] != (Line{OpEqual, "end"}) {
+		t.Errorf("Lines() did not keep the common prefix and suffix")
+	}
+
+	if got[1] != (Line{OpDelete, "a0"}) || got[len(a)+1] != (Line{OpInsert, "b0"}) {
+		t.Errorf("Lines() did not replace the changed region as a whole")
+	}
+}
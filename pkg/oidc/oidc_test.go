@@ -0,0 +1,197 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "nexablog"
+	testKeyID    = "test-key"
+	testNonce    = "nonce-123"
+)
+
+// mockIssuer serves discovery, JWKS and a token endpoint that answers every
+// code with an ID token built from claims and signed with key under kid.
+type mockIssuer struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+	claims map[string]any
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockIssuer{key: key, kid: testKeyID}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, map[string]string{"id_token": m.sign(t)})
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	m.claims = map[string]any{
+		"iss":            m.URL,
+		"aud":            testClientID,
+		"sub":            "user-1",
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"nonce":          testNonce,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+
+	return m
+}
+
+func (m *mockIssuer) sign(t *testing.T) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": m.kid})
+	if err != nil {
+		t.Error(err)
+	}
+
+	payload, err := json.Marshal(m.claims)
+	if err != nil {
+		t.Error(err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Error(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (m *mockIssuer) provider() *Provider {
+	return New(Config{
+		Issuer:      m.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/api/oidc/test/callback",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockIssuer(t)
+
+	claims, err := m.provider().Exchange(context.Background(), "good-code", "verifier", testNonce)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	if claims.Subject != "user-1" || claims.Email != "jane@example.com" || !claims.EmailVerified {
+		t.Errorf("Exchange() claims = %+v", claims)
+	}
+}
+
+func TestExchangeRejectsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		nonce string
+		setup func(m *mockIssuer)
+		want  string
+	}{
+		{
+			name:  "bad nonce",
+			nonce: "other-nonce",
+			setup: func(m *mockIssuer) {},
+			want:  "nonce mismatch",
+		},
+		{
+			name:  "wrong audience",
+			nonce: testNonce,
+			setup: func(m *mockIssuer) { m.claims["aud"] = "someone-else" },
+			want:  "unexpected audience",
+		},
+		{
+			name:  "unknown kid",
+			nonce: testNonce,
+			setup: func(m *mockIssuer) { m.kid = "rotated-away" },
+			want:  "unknown key",
+		},
+		{
+			name:  "expired",
+			nonce: testNonce,
+			setup: func(m *mockIssuer) { m.claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+			want:  "token expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockIssuer(t)
+			tt.setup(m)
+
+			_, err := m.provider().Exchange(context.Background(), "good-code", "verifier", tt.nonce)
+			if !errors.Is(err, ErrInvalidToken) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Exchange() error = %v, want %v: %s", err, ErrInvalidToken, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsBadCode(t *testing.T) {
+	m := newMockIssuer(t)
+
+	_, err := m.provider().Exchange(context.Background(), "bad-code", "verifier", testNonce)
+	if !errors.Is(err, ErrExchange) {
+		t.Errorf("Exchange() error = %v, want %v", err, ErrExchange)
+	}
+}
@@ -0,0 +1,92 @@
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const MaxLength = 80
+
+var replacements = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'ł': "l", 'þ': "th", 'ı': "i", 'ŋ': "ng", '&': "and",
+
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'є': "ye",
+	'і': "i", 'ї': "yi", 'ґ': "g",
+
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+func Make(s string) string {
+	var b strings.Builder
+
+	dash := false
+
+	for _, r := range norm.NFKD.String(strings.ToLower(s)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if rep, ok := replacements[r]; ok {
+			if rep != "" {
+				dash = writeWord(&b, rep, dash)
+			}
+			continue
+		}
+
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			dash = writeWord(&b, string(r), dash)
+			continue
+		}
+
+		dash = b.Len() > 0
+	}
+
+	return truncate(b.String(), MaxLength)
+}
+
+// WithSuffix numbers base, shortening it so that the result still fits in
+// MaxLength. The first slug of a base is the base itself.
+func WithSuffix(base string, n int) string {
+	if n < 2 {
+		return base
+	}
+
+	suffix := "-" + strconv.Itoa(n)
+
+	return truncate(base, MaxLength-len(suffix)) + suffix
+}
+
+func writeWord(b *strings.Builder, s string, dash bool) bool {
+	if dash {
+		b.WriteByte('-')
+	}
+
+	b.WriteString(s)
+
+	return false
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	s = s[:max]
+
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+
+	return strings.Trim(s, "-")
+}
@@ -0,0 +1,93 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"  Go 1.21 released  ", "go-1-21-released"},
+		{"Café au lait", "cafe-au-lait"},
+		{"Crème brûlée", "creme-brulee"},
+		{"Straße", "strasse"},
+		{"Æsir & Œuvre", "aesir-and-oeuvre"},
+		{"Łódź", "lodz"},
+		{"Привет мир", "privet-mir"},
+		{"Объект", "obekt"},
+		{"Αθήνα", "athina"},
+		{"ﬁle", "file"},
+		{"日本語", ""},
+		{"---", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Make(tt.in); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMakeTruncates(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"at a word boundary",
+			strings.Repeat("abcdefghi ", 9),
+			strings.TrimSuffix(strings.Repeat("abcdefghi-", 8), "-"),
+		},
+		{
+			"a single long word",
+			strings.Repeat("a", 100),
+			strings.Repeat("a", MaxLength),
+		},
+	}
+
+	for _, tt := range tests {
+		got := Make(tt.in)
+
+		if got != tt.want {
+			t.Errorf("%s: Make = %q, want %q", tt.name, got, tt.want)
+		}
+
+		if len(got) > MaxLength {
+			t.Errorf("%s: Make is %d bytes long, more than %d", tt.name, len(got), MaxLength)
+		}
+	}
+}
+
+func TestWithSuffix(t *testing.T) {
+	long := strings.TrimSuffix(strings.Repeat("abcdefghi-", 8), "-")
+
+	tests := []struct {
+		base string
+		n    int
+		want string
+	}{
+		{"hello-world", 1, "hello-world"},
+		{"hello-world", 2, "hello-world-2"},
+		{"hello-world", 12, "hello-world-12"},
+		{long, 1, long},
+		{long, 2, strings.Repeat("abcdefghi-", 7) + "2"},
+		{strings.Repeat("a", MaxLength), 3, strings.Repeat("a", MaxLength-2) + "-3"},
+	}
+
+	for _, tt := range tests {
+		got := WithSuffix(tt.base, tt.n)
+
+		if got != tt.want {
+			t.Errorf("WithSuffix(%q, %d) = %q, want %q", tt.base, tt.n, got, tt.want)
+		}
+
+		if len(got) > MaxLength {
+			t.Errorf("WithSuffix(%q, %d) is %d bytes long, more than %d", tt.base, tt.n, len(got), MaxLength)
+		}
+	}
+}
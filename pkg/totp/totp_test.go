@@ -0,0 +1,58 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// The RFC lists 8 digit codes; these are their last Digits digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", v.unix, err)
+		}
+
+		if got != v.code {
+			t.Errorf("Code at %d = %q, want %q", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	step, ok := Validate(rfcSecret, "050471", now)
+	if !ok || step != Step(now) {
+		t.Fatalf("Validate current code = %d, %v; want %d, true", step, ok, Step(now))
+	}
+
+	step, ok = Validate(rfcSecret, "050471", now.Add(Period))
+	if !ok || step != Step(now) {
+		t.Errorf("Validate previous code = %d, %v; want %d, true", step, ok, Step(now))
+	}
+
+	if _, ok := Validate(rfcSecret, "050471", now.Add(2*Period)); ok {
+		t.Error("Validate accepted a code outside the skew")
+	}
+
+	if _, ok := Validate(rfcSecret, "05047", now); ok {
+		t.Error("Validate accepted a short code")
+	}
+}